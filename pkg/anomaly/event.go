@@ -0,0 +1,64 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	EventTypeUndefined EventType = iota
+	EventTypeAnomalyDetected
+	EventTypeAnomalyStatusChanged
+	EventTypeSelfHealingChanged
+)
+
+type EventType int8
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeAnomalyDetected:
+		return "ANOMALY_DETECTED"
+	case EventTypeAnomalyStatusChanged:
+		return "ANOMALY_STATUS_CHANGED"
+	case EventTypeSelfHealingChanged:
+		return "SELF_HEALING_CHANGED"
+	case EventTypeUndefined:
+		fallthrough
+	default:
+		return types.Undefined
+	}
+}
+
+// Event describes a change observed in the state of the Cruise Control anomaly detector.
+type Event struct {
+	Type EventType
+	// Time when the change was observed by the Watcher.
+	Time time.Time
+
+	// Anomaly is set for EventTypeAnomalyDetected and EventTypeAnomalyStatusChanged events.
+	Anomaly *types.Anomaly
+	// PreviousStatus is the status of the anomaly before the change for EventTypeAnomalyStatusChanged events.
+	PreviousStatus types.AnomalyStatus
+
+	// SelfHealingEnabled and SelfHealingDisabled are set for EventTypeSelfHealingChanged events
+	// and hold the self-healing settings after the change.
+	SelfHealingEnabled  []types.AnomalyType
+	SelfHealingDisabled []types.AnomalyType
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultPollInterval = 30 * time.Second
+	DefaultBufferSize   = 100
+)
+
+// ErrWatcherStopped is returned by Poll after Run returned and closed the events channel.
+var ErrWatcherStopped = errors.New("anomaly watcher is stopped")
+
+// StateGetter is implemented by clients which are able to retrieve the Cruise Control state.
+type StateGetter interface {
+	State(ctx context.Context, r *api.StateRequest) (*api.StateResponse, error)
+}
+
+// WatcherConfig contains the configuration parameters for the Watcher.
+type WatcherConfig struct {
+	// Interval between polling the Cruise Control state. DefaultPollInterval is used if not set.
+	Interval time.Duration
+	// Size of the buffer of the events channel. DefaultBufferSize is used if not set.
+	BufferSize int
+	// Whether to emit events for the anomalies which are already reported by Cruise Control at the first poll.
+	IncludeExisting bool
+}
+
+// Watcher polls the anomaly detector state of Cruise Control and emits events on changes.
+type Watcher struct {
+	client StateGetter
+	config WatcherConfig
+	events chan Event
+
+	// mu serializes polls and guards the events channel against sending after it is closed.
+	mu      sync.Mutex
+	stopped bool
+
+	anomalies   map[types.AnomalyID]types.AnomalyStatus
+	selfHealing []types.AnomalyType
+	initialized bool
+}
+
+// NewWatcher returns a new Watcher using the provided client and configuration.
+func NewWatcher(client StateGetter, config WatcherConfig) *Watcher {
+	if config.Interval <= 0 {
+		config.Interval = DefaultPollInterval
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+
+	return &Watcher{
+		client:    client,
+		config:    config,
+		events:    make(chan Event, config.BufferSize),
+		anomalies: make(map[types.AnomalyID]types.AnomalyStatus),
+	}
+}
+
+// Events returns the channel the events are sent to. The channel is closed when Run returns, after which Poll
+// returns ErrWatcherStopped.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Run polls Cruise Control until the context is cancelled. Failing to retrieve the state is logged using
+// the logger from the context and does not stop the Watcher.
+func (w *Watcher) Run(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	defer w.stop()
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			log.Error(err, "failed to poll anomaly detector state")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	close(w.events)
+}
+
+// Poll retrieves the anomaly detector state once and emits events for the changes since the previous poll.
+// It returns ErrWatcherStopped if Run already returned.
+func (w *Watcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return ErrWatcherStopped
+	}
+
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateAnomalyDetector}
+
+	resp, err := w.client.State(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get anomaly detector state: %w", err)
+	}
+	if resp.Result == nil {
+		return errors.New("anomaly detector state is not available")
+	}
+
+	// The state is only updated once every event got delivered, so the changes are reported again by the next poll
+	// if the context is cancelled in the meantime.
+	events, commit := w.diff(resp.Result.AnomalyDetectorState, time.Now())
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case w.events <- event:
+		}
+	}
+	commit()
+	return nil
+}
+
+// diff returns the events describing the changes of the state since the previous poll and the function which
+// records the state as seen.
+func (w *Watcher) diff(state types.AnomalyDetectorState, now time.Time) ([]Event, func()) {
+	events := make([]Event, 0)
+	emit := w.initialized || w.config.IncludeExisting
+
+	current := make(map[types.AnomalyID]types.AnomalyStatus)
+	for _, a := range state.Anomalies() {
+		a := a
+		current[a.AnomalyID] = a.Status
+
+		prev, seen := w.anomalies[a.AnomalyID]
+		switch {
+		case !seen && emit:
+			events = append(events, Event{
+				Type:    EventTypeAnomalyDetected,
+				Time:    now,
+				Anomaly: &a,
+			})
+		case seen && prev != a.Status:
+			events = append(events, Event{
+				Type:           EventTypeAnomalyStatusChanged,
+				Time:           now,
+				Anomaly:        &a,
+				PreviousStatus: prev,
+			})
+		}
+	}
+	enabled := sortedAnomalyTypes(state.SelfHealingEnabled)
+	if w.initialized && !equalAnomalyTypes(w.selfHealing, enabled) {
+		events = append(events, Event{
+			Type:                EventTypeSelfHealingChanged,
+			Time:                now,
+			SelfHealingEnabled:  state.SelfHealingEnabled,
+			SelfHealingDisabled: state.SelfHealingDisabled,
+		})
+	}
+
+	return events, func() {
+		// Anomalies which are no longer reported by Cruise Control are forgotten to keep memory usage bounded.
+		w.anomalies = current
+		w.selfHealing = enabled
+		w.initialized = true
+	}
+}
+
+func sortedAnomalyTypes(t []types.AnomalyType) []types.AnomalyType {
+	s := make([]types.AnomalyType, len(t))
+	copy(s, t)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
+
+func equalAnomalyTypes(a, b []types.AnomalyType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+type fakeStateGetter struct {
+	state types.AnomalyDetectorState
+}
+
+func (f *fakeStateGetter) State(_ context.Context, _ *api.StateRequest) (*api.StateResponse, error) {
+	return &api.StateResponse{Result: &types.StateResult{AnomalyDetectorState: f.state}}, nil
+}
+
+func goalViolation(id string, status types.AnomalyStatus) types.AnomalyDetails {
+	return types.AnomalyDetails{AnomalyID: types.AnomalyID(id), Status: status}
+}
+
+// diff returns the events of the changes and records the state as seen.
+func diff(w *Watcher, state types.AnomalyDetectorState, now time.Time) []Event {
+	events, commit := w.diff(state, now)
+	commit()
+	return events
+}
+
+func TestWatcherDiff(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Existing anomalies", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := types.AnomalyDetectorState{
+			RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusDetected)},
+		}
+
+		w := NewWatcher(&fakeStateGetter{}, WatcherConfig{})
+		g.Expect(diff(w, state, now)).To(BeEmpty())
+
+		w = NewWatcher(&fakeStateGetter{}, WatcherConfig{IncludeExisting: true})
+		events := diff(w, state, now)
+		g.Expect(events).To(HaveLen(1))
+		g.Expect(events[0].Type).To(Equal(EventTypeAnomalyDetected))
+		g.Expect(events[0].Anomaly.Type).To(Equal(types.AnomalyTypeGoalViolation))
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		w := NewWatcher(&fakeStateGetter{}, WatcherConfig{})
+		g.Expect(diff(w, types.AnomalyDetectorState{
+			RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusDetected)},
+		}, now)).To(BeEmpty())
+
+		events := diff(w, types.AnomalyDetectorState{
+			RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusFixStarted)},
+			RecentBrokerFailures: []types.AnomalyDetails{goalViolation("b", types.AnomalyStatusDetected)},
+			SelfHealingEnabled:   []types.AnomalyType{types.AnomalyTypeBrokerFailure},
+		}, now)
+		g.Expect(events).To(HaveLen(3))
+		g.Expect(events[0].Type).To(Equal(EventTypeAnomalyStatusChanged))
+		g.Expect(events[0].PreviousStatus).To(Equal(types.AnomalyStatusDetected))
+		g.Expect(events[0].Anomaly.Status).To(Equal(types.AnomalyStatusFixStarted))
+		g.Expect(events[1].Type).To(Equal(EventTypeAnomalyDetected))
+		g.Expect(events[1].Anomaly.AnomalyID).To(Equal(types.AnomalyID("b")))
+		g.Expect(events[2].Type).To(Equal(EventTypeSelfHealingChanged))
+
+		// Unchanged state and self-healing settings in different order do not produce events.
+		g.Expect(diff(w, types.AnomalyDetectorState{
+			RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusFixStarted)},
+			RecentBrokerFailures: []types.AnomalyDetails{goalViolation("b", types.AnomalyStatusDetected)},
+			SelfHealingEnabled:   []types.AnomalyType{types.AnomalyTypeBrokerFailure},
+		}, now)).To(BeEmpty())
+
+		// Forgotten anomalies are reported again when they reappear.
+		g.Expect(diff(w, types.AnomalyDetectorState{
+			SelfHealingEnabled: []types.AnomalyType{types.AnomalyTypeBrokerFailure},
+		}, now)).To(BeEmpty())
+		events = diff(w, types.AnomalyDetectorState{
+			RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusFixStarted)},
+			SelfHealingEnabled:   []types.AnomalyType{types.AnomalyTypeBrokerFailure},
+		}, now)
+		g.Expect(events).To(HaveLen(1))
+		g.Expect(events[0].Type).To(Equal(EventTypeAnomalyDetected))
+	})
+}
+
+func TestWatcherPollAfterRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeStateGetter{state: types.AnomalyDetectorState{
+		RecentGoalViolations: []types.AnomalyDetails{goalViolation("a", types.AnomalyStatusDetected)},
+	}}
+	w := NewWatcher(client, WatcherConfig{IncludeExisting: true, Interval: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	g.Eventually(w.Events()).Should(Receive())
+	cancel()
+	g.Eventually(done).Should(Receive(BeNil()))
+	g.Eventually(w.Events()).Should(BeClosed())
+
+	g.Expect(w.Poll(context.Background())).To(MatchError(ErrWatcherStopped))
+}
+
+func TestWatcherPollCancelled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeStateGetter{state: types.AnomalyDetectorState{
+		RecentGoalViolations: []types.AnomalyDetails{
+			goalViolation("a", types.AnomalyStatusDetected),
+			goalViolation("b", types.AnomalyStatusDetected),
+		},
+	}}
+	w := NewWatcher(client, WatcherConfig{IncludeExisting: true, BufferSize: 1})
+
+	// The buffer only fits the first event, so the delivery of the second one is interrupted.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g.Expect(w.Poll(ctx)).To(MatchError(context.DeadlineExceeded))
+	g.Expect(w.Events()).To(Receive())
+
+	// Both anomalies are reported again as the interrupted poll did not record them as seen.
+	done := make(chan error)
+	go func() { done <- w.Poll(context.Background()) }()
+	for _, id := range []string{"a", "b"} {
+		var event Event
+		g.Eventually(w.Events()).Should(Receive(&event))
+		g.Expect(event.Type).To(Equal(EventTypeAnomalyDetected))
+		g.Expect(event.Anomaly.AnomalyID).To(Equal(types.AnomalyID(id)))
+	}
+	g.Eventually(done).Should(Receive(BeNil()))
+}
@@ -17,9 +17,6 @@ limitations under the License.
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/pkg/errors"
@@ -86,41 +83,8 @@ func DemoteBrokerRequestWithDefaults() *DemoteBrokerRequest {
 	}
 }
 
-type DemoteBrokerResponse struct {
-	types.GenericResponse
-
-	Result *types.OptimizationResult
-}
+type DemoteBrokerResponse Response[types.OptimizationResult]
 
 func (r *DemoteBrokerResponse) UnmarshalResponse(resp *http.Response) error {
-	if err := r.GenericResponse.UnmarshalResponse(resp); err != nil {
-		return fmt.Errorf("failed to parse HTTP response metadata: %w", err)
-	}
-
-	var bodyBytes []byte
-	var err error
-
-	bodyBytes, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var d interface{}
-	switch resp.StatusCode {
-	case http.StatusOK:
-		r.Result = &types.OptimizationResult{}
-		d = r.Result
-	case http.StatusAccepted:
-		r.Progress = &types.ProgressResult{}
-		d = r.Progress
-	default:
-		r.Error = &types.APIError{}
-		d = r.Error
-	}
-
-	if err = json.Unmarshal(bodyBytes, d); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	return nil
+	return (*Response[types.OptimizationResult])(r).UnmarshalResponse(resp)
 }
@@ -17,9 +17,6 @@ limitations under the License.
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/banzaicloud/go-cruise-control/pkg/types"
@@ -49,38 +46,8 @@ func KafkaClusterStateRequestWithDefaults() *KafkaClusterStateRequest {
 	}
 }
 
-type KafkaClusterStateResponse struct {
-	types.GenericResponse
-
-	Result *types.KafkaClusterState
-}
+type KafkaClusterStateResponse Response[types.KafkaClusterState]
 
 func (r *KafkaClusterStateResponse) UnmarshalResponse(resp *http.Response) error {
-	if err := r.GenericResponse.UnmarshalResponse(resp); err != nil {
-		return fmt.Errorf("failed to parse HTTP response metadata: %w", err)
-	}
-
-	var bodyBytes []byte
-	var err error
-
-	bodyBytes, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var d interface{}
-	switch resp.StatusCode {
-	case http.StatusOK:
-		r.Result = &types.KafkaClusterState{}
-		d = r.Result
-	default:
-		r.Error = &types.APIError{}
-		d = r.Error
-	}
-
-	if err = json.Unmarshal(bodyBytes, d); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	return nil
+	return (*Response[types.KafkaClusterState])(r).UnmarshalResponse(resp)
 }
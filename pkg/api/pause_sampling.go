@@ -17,9 +17,6 @@ limitations under the License.
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/banzaicloud/go-cruise-control/pkg/types"
@@ -44,38 +41,8 @@ func PauseSamplingRequestWithDefaults() *PauseSamplingRequest {
 	return &PauseSamplingRequest{}
 }
 
-type PauseSamplingResponse struct {
-	types.GenericResponse
-
-	Result *types.SamplingResult
-}
+type PauseSamplingResponse Response[types.SamplingResult]
 
 func (r *PauseSamplingResponse) UnmarshalResponse(resp *http.Response) error {
-	if err := r.GenericResponse.UnmarshalResponse(resp); err != nil {
-		return fmt.Errorf("failed to parse HTTP response metadata: %w", err)
-	}
-
-	var bodyBytes []byte
-	var err error
-
-	bodyBytes, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var d interface{}
-	switch resp.StatusCode {
-	case http.StatusOK:
-		r.Result = &types.SamplingResult{}
-		d = r.Result
-	default:
-		r.Error = &types.APIError{}
-		d = r.Error
-	}
-
-	if err = json.Unmarshal(bodyBytes, d); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	return nil
+	return (*Response[types.SamplingResult])(r).UnmarshalResponse(resp)
 }
@@ -0,0 +1,86 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Response is the API response of endpoints returning a result of type T on success.
+//
+// Successful (200) responses are decoded into Result, in progress (202) responses into Progress and every other
+// response into Error. Empty bodies leave the decoded value empty, while error responses which are not valid JSON
+// are reported using the body as the error message.
+//
+// The response types of the endpoints are distinct types defined as Response[T] which decode the responses using it,
+// so the ones sharing a result type, e.g. RebalanceResponse and ProposalsResponse, can still be told apart.
+type Response[T any] struct {
+	types.GenericResponse
+
+	Result *T
+}
+
+func (r *Response[T]) UnmarshalResponse(resp *http.Response) error {
+	if err := r.GenericResponse.UnmarshalResponse(resp); err != nil {
+		return fmt.Errorf("failed to parse HTTP response metadata: %w", err)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+	bodyBytes = bytes.TrimSpace(bodyBytes)
+
+	var d interface{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		r.Result = new(T)
+		d = r.Result
+	case http.StatusAccepted:
+		r.Progress = &types.ProgressResult{}
+		d = r.Progress
+	default:
+		r.Error = &types.APIError{}
+		if len(bodyBytes) == 0 || !json.Valid(bodyBytes) || bodyBytes[0] != '{' {
+			r.Error.Message = string(bodyBytes)
+			if r.Error.Message == "" {
+				r.Error.Message = http.StatusText(resp.StatusCode)
+			}
+			r.Error.Status = strconv.Itoa(resp.StatusCode)
+			r.Error.URL = r.RequestURL
+			return nil
+		}
+		d = r.Error
+	}
+
+	if len(bodyBytes) == 0 {
+		return nil
+	}
+
+	if err = json.Unmarshal(bodyBytes, d); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return nil
+}
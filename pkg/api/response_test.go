@@ -0,0 +1,161 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		result      *types.UserTaskState
+		progress    *types.ProgressResult
+		err         *types.APIError
+		decodeError bool
+	}{
+		{
+			name:   "OK",
+			status: http.StatusOK,
+			body:   `{"version":1,"userTasks":[{"UserTaskId":"5a3c","Status":"Completed"}]}`,
+			result: &types.UserTaskState{
+				Version:   types.Version{Version: 1},
+				UserTasks: []types.UserTaskInfo{{UserTaskID: "5a3c", Status: types.UserTaskStatusCompleted}},
+			},
+		},
+		{
+			name:   "OK with empty body",
+			status: http.StatusOK,
+			body:   " \n",
+			result: &types.UserTaskState{},
+		},
+		{
+			name:   "In progress",
+			status: http.StatusAccepted,
+			body:   `{"version":1,"progress":[{"operation":"Get user tasks","operationProgress":[{"step":"PENDING"}]}]}`,
+			progress: &types.ProgressResult{
+				Version: 1,
+				Progress: []types.Operation{{
+					Operation: "Get user tasks",
+					Progress:  []types.OperationStep{{Step: "PENDING"}},
+				}},
+			},
+		},
+		{
+			name:     "In progress with empty body",
+			status:   http.StatusAccepted,
+			progress: &types.ProgressResult{},
+		},
+		{
+			name:   "Cruise Control error",
+			status: http.StatusInternalServerError,
+			body:   `{"errorMessage":"Error processing GET request","stackTrace":"java.lang.IllegalStateException"}`,
+			err: &types.APIError{
+				ErrorMessage: "Error processing GET request",
+				StackTrace:   "java.lang.IllegalStateException",
+			},
+		},
+		{
+			name:   "Non-JSON error",
+			status: http.StatusBadGateway,
+			body:   "<html><body>Bad Gateway</body></html>",
+			err: &types.APIError{
+				Message: "<html><body>Bad Gateway</body></html>",
+				Status:  "502",
+				URL:     testURL,
+			},
+		},
+		{
+			name:   "JSON error which is not an object",
+			status: http.StatusNotFound,
+			body:   `"not found"`,
+			err: &types.APIError{
+				Message: `"not found"`,
+				Status:  "404",
+				URL:     testURL,
+			},
+		},
+		{
+			name:   "Empty error",
+			status: http.StatusServiceUnavailable,
+			err: &types.APIError{
+				Message: http.StatusText(http.StatusServiceUnavailable),
+				Status:  "503",
+				URL:     testURL,
+			},
+		},
+		{
+			name:        "Invalid JSON result",
+			status:      http.StatusOK,
+			body:        `{"userTasks":`,
+			result:      &types.UserTaskState{},
+			decodeError: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			resp := &UserTasksResponse{}
+			err := resp.UnmarshalResponse(newHTTPResponse(test.status, "application/json", test.body))
+			if test.decodeError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(resp.StatusCode).To(Equal(test.status))
+			g.Expect(resp.RequestURL).To(Equal(testURL))
+			g.Expect(resp.Result).To(Equal(test.result))
+			g.Expect(resp.Progress).To(Equal(test.progress))
+			g.Expect(resp.InProgress()).To(Equal(test.progress != nil))
+			g.Expect(resp.Error).To(Equal(test.err))
+			g.Expect(resp.Failed()).To(Equal(test.err != nil))
+		})
+	}
+}
+
+func TestResponseTypes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// The response types of the endpoints sharing a result type are distinct, so they can be told apart.
+	endpoint := func(resp types.APIResponse) string {
+		switch resp.(type) {
+		case *RebalanceResponse:
+			return "rebalance"
+		case *ProposalsResponse:
+			return "proposals"
+		default:
+			return ""
+		}
+	}
+
+	resp := &RebalanceResponse{}
+	body := `{"summary":{"numReplicaMovements":3},"version":1}`
+	g.Expect(resp.UnmarshalResponse(newHTTPResponse(http.StatusOK, "application/json", body))).To(Succeed())
+	g.Expect(resp.Result.Summary.NumReplicaMovements).To(Equal(int32(3)))
+	g.Expect(endpoint(resp)).To(Equal("rebalance"))
+	g.Expect(endpoint(&ProposalsResponse{})).To(Equal("proposals"))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// TextResponse holds the human-readable (plain text) response of any Cruise Control endpoint.
+type TextResponse struct {
+	types.GenericResponse
+
+	// The response body as returned by Cruise Control.
+	Body        string
+	ContentType string
+}
+
+func (r *TextResponse) UnmarshalResponse(resp *http.Response) error {
+	if err := r.GenericResponse.UnmarshalResponse(resp); err != nil {
+		return fmt.Errorf("failed to parse HTTP response metadata: %w", err)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+	r.Body = string(bodyBytes)
+	r.ContentType = resp.Header.Get(types.ContentTypeHTTPHeader)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusAccepted:
+		r.Progress = &types.ProgressResult{}
+	default:
+		r.Error = &types.APIError{
+			Message: r.Body,
+			Status:  strconv.Itoa(resp.StatusCode),
+			URL:     r.RequestURL,
+		}
+		if r.Error.Message == "" {
+			r.Error.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+
+	return nil
+}
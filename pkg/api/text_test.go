@@ -0,0 +1,107 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const testURL = "http://localhost:8090/kafkacruisecontrol/state?json=false"
+
+// newHTTPResponse returns the HTTP response with the status code, content type and body as received by the client.
+func newHTTPResponse(status int, contentType, body string) *http.Response {
+	rec := httptest.NewRecorder()
+	if contentType != "" {
+		rec.Header().Set(types.ContentTypeHTTPHeader, contentType)
+	}
+	rec.WriteHeader(status)
+	_, _ = rec.WriteString(body)
+
+	resp := rec.Result()
+	resp.Request = httptest.NewRequest(http.MethodGet, testURL, nil)
+	return resp
+}
+
+func TestTextResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		inProgress bool
+		message    string
+		err        interface{}
+	}{
+		{
+			name:   "OK",
+			status: http.StatusOK,
+			body:   "MonitorState: {state: RUNNING(100.000% trained)}",
+		},
+		{
+			name:       "In progress",
+			status:     http.StatusAccepted,
+			body:       "Operation: Get customized proposals",
+			inProgress: true,
+		},
+		{
+			name:    "Unauthorized",
+			status:  http.StatusUnauthorized,
+			body:    "Unauthorized",
+			message: "Unauthorized",
+			err:     new(*types.PermissionDeniedError),
+		},
+		{
+			name:    "Unavailable with empty body",
+			status:  http.StatusServiceUnavailable,
+			message: http.StatusText(http.StatusServiceUnavailable),
+			err:     new(*types.NotReadyError),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			resp := &TextResponse{}
+			g.Expect(resp.UnmarshalResponse(newHTTPResponse(test.status, "text/plain", test.body))).To(Succeed())
+			g.Expect(resp.Body).To(Equal(test.body))
+			g.Expect(resp.ContentType).To(Equal("text/plain"))
+			g.Expect(resp.StatusCode).To(Equal(test.status))
+			g.Expect(resp.InProgress()).To(Equal(test.inProgress))
+
+			if test.err == nil {
+				g.Expect(resp.Failed()).To(BeFalse())
+				return
+			}
+			g.Expect(resp.Failed()).To(BeTrue())
+			g.Expect(resp.Error.Message).To(Equal(test.message))
+			g.Expect(resp.Error.URL).To(Equal(testURL))
+
+			var ccErr *types.CruiseControlError
+			g.Expect(errors.As(resp.Err(), &ccErr)).To(BeTrue())
+			g.Expect(ccErr.StatusCode).To(Equal(test.status))
+			g.Expect(errors.As(resp.Err(), test.err)).To(BeTrue())
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestNewRecord(t *testing.T) {
+	t.Run("Rebalance", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		resp := &api.RebalanceResponse{
+			GenericResponse: types.GenericResponse{TaskID: "task-1"},
+			Result: &types.OptimizationResult{
+				Proposals: []types.ExecutionProposal{{OldReplicas: []int32{3, 1}, NewReplicas: []int32{1, 2}}},
+				Summary:   types.OptimizerResult{DataToMoveMB: 100, NumReplicaMovements: 1},
+			},
+		}
+		params := url.Values{"reason": {"weekly"}, "doAs": {"admin"}, "destination_broker_ids": {"2,5"}}
+
+		r := NewRecord("kafka", api.EndpointRebalance, "POST", params, resp, nil)
+
+		g.Expect(r.Cluster).To(Equal("kafka"))
+		g.Expect(r.Reason).To(Equal("weekly"))
+		g.Expect(r.DoAs).To(Equal("admin"))
+		g.Expect(r.UserTaskID).To(Equal("task-1"))
+		g.Expect(r.Brokers).To(Equal([]int32{1, 2, 3, 5}))
+		g.Expect(r.Result).To(Equal(Result{Status: ResultStatusCompleted, DataToMoveMB: 100, NumReplicaMovements: 1}))
+	})
+
+	t.Run("Failed request", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		params := url.Values{"brokerid_and_logdirs": {"4-/var/lib/kafka"}}
+		r := NewRecord("", api.EndpointDemoteBroker, "POST", params, &api.DemoteBrokerResponse{}, errors.New("boom"))
+
+		g.Expect(r.Brokers).To(Equal([]int32{4}))
+		g.Expect(r.Result).To(Equal(Result{Status: ResultStatusFailed, Error: "boom"}))
+	})
+}
+
+func TestStores(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: base, Cluster: "a", Endpoint: api.EndpointRebalance, Brokers: []int32{1, 2}},
+		{Time: base.Add(time.Hour), Cluster: "b", Endpoint: api.EndpointAddBroker, Brokers: []int32{3}},
+		{Time: base.Add(2 * time.Hour), Cluster: "a", Endpoint: api.EndpointRemoveBroker, Brokers: []int32{2}},
+	}
+
+	jsonLines, err := NewJSONLinesStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonLines.Close()
+
+	stores := map[string]Store{
+		"Memory":     &MemoryStore{},
+		"JSON lines": jsonLines,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+
+			for _, r := range records {
+				g.Expect(store.Append(ctx, r)).To(Succeed())
+			}
+
+			all, err := store.Query(ctx, Query{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(all).To(HaveLen(3))
+			g.Expect(all[0].Endpoint).To(Equal(api.EndpointRebalance))
+			g.Expect(all[0].Time.Equal(base)).To(BeTrue())
+
+			byTime, err := store.Query(ctx, Query{Since: base.Add(time.Minute), Until: base.Add(2 * time.Hour)})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(byTime).To(HaveLen(1))
+			g.Expect(byTime[0].Cluster).To(Equal("b"))
+
+			byCluster, err := store.Query(ctx, Query{Clusters: []string{"a"}, Brokers: []int32{2}, Limit: 1})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(byCluster).To(HaveLen(1))
+			g.Expect(byCluster[0].Endpoint).To(Equal(api.EndpointRemoveBroker))
+
+			byEndpoint, err := store.Query(ctx, Query{Endpoints: []types.APIEndpoint{api.EndpointAddBroker}})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(byEndpoint).To(HaveLen(1))
+			g.Expect(byEndpoint[0].Brokers).To(Equal([]int32{3}))
+		})
+	}
+}
+
+func TestJSONLinesStoreIncompleteLine(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	g.Expect(os.WriteFile(path, []byte(`{"cluster":"a"}`+"\n"+`{"cluster":"b","endp`), 0o600)).To(Succeed())
+
+	store, err := NewJSONLinesStore(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer store.Close()
+
+	g.Expect(store.Append(ctx, Record{Cluster: "c"})).To(Succeed())
+	g.Expect(store.Append(ctx, Record{Cluster: "d"})).To(Succeed())
+
+	records, err := store.Query(ctx, Query{})
+	g.Expect(err).NotTo(HaveOccurred())
+	clusters := make([]string, 0, len(records))
+	for _, r := range records {
+		clusters = append(clusters, r.Cluster)
+	}
+	g.Expect(clusters).To(Equal([]string{"a", "c", "d"}))
+}
@@ -0,0 +1,20 @@
+module github.com/banzaicloud/go-cruise-control/pkg/audit/boltstore
+
+go 1.21.4
+
+require (
+	github.com/banzaicloud/go-cruise-control v0.0.0
+	github.com/onsi/gomega v1.30.0
+	go.etcd.io/bbolt v1.3.8
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/banzaicloud/go-cruise-control => ../../..
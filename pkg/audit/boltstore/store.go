@@ -0,0 +1,118 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package boltstore provides an audit.Store backed by a bbolt database. It is a separate module so that the
+// client does not depend on bbolt unless the store is used.
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+)
+
+const fileMode = 0o600
+
+var bucket = []byte("records")
+
+// Store is an audit.Store keeping the records in a bbolt database. Records are committed to the database
+// before Append returns, so they survive restarts of the process.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open returns a Store keeping the records in the database at path. The database is created if it does not exist.
+// Only one process can have the database open at a time.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, fileMode, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create audit bucket: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Append stores the record with the next sequence number of the bucket as key, so that records are iterated in
+// the order they were appended.
+func (s *Store) Append(_ context.Context, r audit.Record) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8) //nolint:gomnd
+		binary.BigEndian.PutUint64(key, seq)
+		return b.Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Query iterates the records from the latest one, so that only the latest records are read if the query
+// is limited.
+func (s *Store) Query(ctx context.Context, q audit.Query) ([]audit.Record, error) {
+	records := make([]audit.Record, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var record audit.Record
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("failed to decode audit record: %w", err)
+			}
+			if !q.Matches(record) {
+				continue
+			}
+			records = append(records, record)
+			if q.Limit > 0 && len(records) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(records)
+	return records, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boltstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+)
+
+func TestStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []audit.Record{
+		{Time: base, Cluster: "a", Endpoint: api.EndpointRebalance, Brokers: []int32{1, 2}},
+		{Time: base.Add(time.Hour), Cluster: "b", Endpoint: api.EndpointAddBroker, Brokers: []int32{3}},
+		{Time: base.Add(2 * time.Hour), Cluster: "a", Endpoint: api.EndpointRemoveBroker, Brokers: []int32{2}},
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.db")
+	store, err := Open(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, r := range records {
+		g.Expect(store.Append(ctx, r)).To(Succeed())
+	}
+	g.Expect(store.Close()).To(Succeed())
+
+	// Records are kept when the database is opened again.
+	store, err = Open(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer store.Close()
+
+	all, err := store.Query(ctx, audit.Query{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(all).To(HaveLen(3))
+	g.Expect(all[0].Endpoint).To(Equal(api.EndpointRebalance))
+	g.Expect(all[0].Time.Equal(base)).To(BeTrue())
+
+	latest, err := store.Query(ctx, audit.Query{Limit: 2})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(latest).To(HaveLen(2))
+	g.Expect(latest[0].Cluster).To(Equal("b"))
+	g.Expect(latest[1].Endpoint).To(Equal(api.EndpointRemoveBroker))
+
+	byCluster, err := store.Query(ctx, audit.Query{Clusters: []string{"a"}, Brokers: []int32{2}, Limit: 1})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(byCluster).To(HaveLen(1))
+	g.Expect(byCluster[0].Endpoint).To(Equal(api.EndpointRemoveBroker))
+
+	g.Expect(store.Append(ctx, audit.Record{Time: base.Add(3 * time.Hour), Cluster: "c"})).To(Succeed())
+	byTime, err := store.Query(ctx, audit.Query{Since: base.Add(time.Minute), Until: base.Add(3 * time.Hour)})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(byTime).To(HaveLen(2))
+	g.Expect(byTime[0].Cluster).To(Equal("b"))
+}
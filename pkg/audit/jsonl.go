@@ -0,0 +1,131 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const jsonLinesFileMode = 0o600
+
+// JSONLinesStore is a Store appending the records to a file with one JSON encoded record per line.
+// Records are written to the file before Append returns, so they survive restarts of the process.
+type JSONLinesStore struct {
+	mu   sync.Mutex
+	file *os.File
+	// partial is whether the file might end with an incomplete line which the next record must not be appended to.
+	partial bool
+}
+
+// NewJSONLinesStore returns a Store appending the records to the file at path. The file is created if it does
+// not exist. If the file ends with an incomplete line, e.g. as the process was terminated while writing it, the
+// line is terminated before the first record is appended so that the record is not lost with it.
+func NewJSONLinesStore(path string) (*JSONLinesStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, jsonLinesFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &JSONLinesStore{file: f, partial: true}, nil
+}
+
+// terminate appends a newline to the file unless it is empty or already ends with one.
+func (s *JSONLinesStore) terminate() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err = s.file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = s.file.Write([]byte{'\n'})
+	}
+	return err
+}
+
+func (s *JSONLinesStore) Append(_ context.Context, r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.partial {
+		if err = s.terminate(); err != nil {
+			return fmt.Errorf("failed to terminate last line of audit log: %w", err)
+		}
+		s.partial = false
+	}
+	if _, err = s.file.Write(line); err != nil {
+		s.partial = true
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err = s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return nil
+}
+
+// Query reads the whole file and returns the matching records. Lines which cannot be decoded are skipped
+// as the last line might be incomplete if the process was terminated while writing it.
+func (s *JSONLinesStore) Query(ctx context.Context, q Query) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := io.NewSectionReader(s.file, 0, 1<<62) //nolint:gomnd
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24) //nolint:gomnd
+
+	records := make([]Record, 0)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if q.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return q.limit(records), nil
+}
+
+// Close closes the underlying file.
+func (s *JSONLinesStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is a Store keeping the records in memory. The zero value is ready to use.
+type MemoryStore struct {
+	// Limit is the maximum number of records kept. The oldest records are dropped if it is exceeded.
+	// All records are kept if not set.
+	Limit int
+
+	mu      sync.RWMutex
+	records []Record
+}
+
+func (s *MemoryStore) Append(_ context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, r)
+	if s.Limit > 0 && len(s.records) > s.Limit {
+		s.records = append([]Record(nil), s.records[len(s.records)-s.Limit:]...)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Query(_ context.Context, q Query) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]Record, 0)
+	for _, r := range s.records {
+		if q.Matches(r) {
+			records = append(records, r)
+		}
+	}
+	return q.limit(records), nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	ResultStatusCompleted  ResultStatus = "completed"
+	ResultStatusInProgress ResultStatus = "in-progress"
+	ResultStatusFailed     ResultStatus = "failed"
+)
+
+// ResultStatus describes whether the audited request succeeded.
+type ResultStatus string
+
+// brokerParams are the query parameters of the mutating endpoints holding broker IDs.
+var brokerParams = []string{
+	"brokerid",
+	"destination_broker_ids",
+	"drop_recently_demoted_brokers",
+	"drop_recently_removed_brokers",
+	"brokerid_and_logdirs",
+}
+
+// Store persists audit records. Implementations must be safe for concurrent use. The package provides
+// MemoryStore and JSONLinesStore; a store backed by bbolt is provided by the separate boltstore module to keep
+// bbolt out of the dependencies of the client.
+type Store interface {
+	// Append adds the record to the store.
+	Append(ctx context.Context, r Record) error
+	// Query returns the records matching the query in the order they were appended.
+	Query(ctx context.Context, q Query) ([]Record, error)
+}
+
+// Record describes a mutating request sent to Cruise Control.
+type Record struct {
+	// Time the request was started at.
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Cluster is the name of the Kafka cluster the client was configured with.
+	Cluster  string            `json:"cluster,omitempty"`
+	Endpoint types.APIEndpoint `json:"endpoint"`
+	Method   string            `json:"method"`
+	// Params holds the URL encoded query parameters of the request.
+	Params     string `json:"params,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	DoAs       string `json:"doAs,omitempty"`
+	UserTaskID string `json:"userTaskID,omitempty"`
+	// Brokers affected by the request either as parameters or as part of the proposed partition movements.
+	Brokers []int32 `json:"brokers,omitempty"`
+	Result  Result  `json:"result"`
+}
+
+// Result summarizes the response of an audited request.
+type Result struct {
+	Status ResultStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	// The following fields are only set for requests responding with optimization results.
+	DataToMoveMB                   int64 `json:"dataToMoveMB,omitempty"`
+	NumReplicaMovements            int32 `json:"numReplicaMovements,omitempty"`
+	NumIntraBrokerReplicaMovements int32 `json:"numIntraBrokerReplicaMovements,omitempty"`
+	NumLeaderMovements             int32 `json:"numLeaderMovements,omitempty"`
+}
+
+// NewRecord returns the audit record of a request with the provided query parameters, response and error.
+// Reason and DoAs are taken from the parameters.
+func NewRecord(cluster string, e types.APIEndpoint, method string, params url.Values, resp types.APIResponse,
+	err error,
+) Record {
+	r := Record{
+		Cluster:  cluster,
+		Endpoint: e,
+		Method:   method,
+		Params:   params.Encode(),
+		Reason:   params.Get("reason"),
+		DoAs:     params.Get("doAs"),
+	}
+
+	brokers := make(map[int32]bool)
+	for _, p := range brokerParams {
+		for _, v := range params[p] {
+			for _, b := range strings.Split(v, ",") {
+				// Values of brokerid_and_logdirs are in <broker>-<logdir> format.
+				b, _, _ = strings.Cut(b, "-")
+				if id, err := strconv.ParseInt(b, 10, 32); err == nil {
+					brokers[int32(id)] = true
+				}
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		r.Result = Result{Status: ResultStatusFailed, Error: err.Error()}
+	case resp != nil && resp.InProgress():
+		r.Result = Result{Status: ResultStatusInProgress}
+	default:
+		r.Result = Result{Status: ResultStatusCompleted}
+	}
+
+	if t, ok := resp.(interface{ UserTaskID() string }); ok {
+		r.UserTaskID = t.UserTaskID()
+	}
+	if o := optimizationResult(resp); o != nil {
+		s := o.Summary
+		r.Result.DataToMoveMB = s.DataToMoveMB
+		r.Result.NumReplicaMovements = s.NumReplicaMovements
+		r.Result.NumIntraBrokerReplicaMovements = s.NumIntraBrokerReplicaMovements
+		r.Result.NumLeaderMovements = s.NumLeaderMovements
+		for _, p := range o.Proposals {
+			for _, b := range append(p.OldReplicas, p.NewReplicas...) {
+				brokers[b] = true
+			}
+		}
+	}
+
+	for b := range brokers {
+		r.Brokers = append(r.Brokers, b)
+	}
+	sort.Slice(r.Brokers, func(i, j int) bool { return r.Brokers[i] < r.Brokers[j] })
+
+	return r
+}
+
+// optimizationResult returns the optimization result of the responses of the endpoints proposing partition
+// movements. It returns nil for every other response.
+func optimizationResult(resp types.APIResponse) *types.OptimizationResult {
+	switch o := resp.(type) {
+	case *api.AddBrokerResponse:
+		return o.Result
+	case *api.DemoteBrokerResponse:
+		return o.Result
+	case *api.FixOfflineReplicasResponse:
+		return o.Result
+	case *api.ProposalsResponse:
+		return o.Result
+	case *api.RebalanceResponse:
+		return o.Result
+	case *api.RemoveBrokerResponse:
+		return o.Result
+	case *api.TopicConfigurationResponse:
+		return o.Result
+	case *api.Response[types.OptimizationResult]:
+		return o.Result
+	default:
+		return nil
+	}
+}
+
+// Query selects audit records. Empty fields match every record.
+type Query struct {
+	// Records started before Since are not matched.
+	Since time.Time
+	// Records started at or after Until are not matched.
+	Until     time.Time
+	Endpoints []types.APIEndpoint
+	Clusters  []string
+	// Records affecting any of the Brokers are matched.
+	Brokers []int32
+	// Limit is the maximum number of records returned. If exceeded, the latest records are returned.
+	Limit int
+}
+
+// Matches returns true if the record is selected by the query.
+func (q Query) Matches(r Record) bool {
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	if len(q.Endpoints) > 0 && !contains(q.Endpoints, r.Endpoint) {
+		return false
+	}
+	if len(q.Clusters) > 0 && !contains(q.Clusters, r.Cluster) {
+		return false
+	}
+	if len(q.Brokers) > 0 {
+		for _, b := range r.Brokers {
+			if contains(q.Brokers, b) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (q Query) limit(records []Record) []Record {
+	if q.Limit > 0 && len(records) > q.Limit {
+		return records[len(records)-q.Limit:]
+	}
+	return records
+}
+
+func contains[T comparable](s []T, v T) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Client is implemented by clients which are able to retrieve the cluster load, the cluster state and
+// the optimization proposals from Cruise Control.
+type Client interface {
+	KafkaClusterLoad(ctx context.Context, r *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error)
+	KafkaClusterState(ctx context.Context, r *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error)
+	Proposals(ctx context.Context, r *api.ProposalsRequest) (*api.ProposalsResponse, error)
+}
+
+// Provisioner is implemented by clients which are able to send rightsize requests to Cruise Control.
+type Provisioner interface {
+	Rightsize(ctx context.Context, r *api.RightsizeRequest) (*api.RightsizeResponse, error)
+}
+
+// Calculate retrieves the cluster load from Cruise Control and returns the capacity plan of the cluster
+// cross-checked with the provision status of the cached optimization proposals. The cross-check is
+// omitted if Cruise Control is not ready to generate proposals.
+//
+// The highest replication factor of the topics is retrieved from the Kafka cluster state unless it is set in
+// the configuration. Calculate only reads the state of Cruise Control, see Provision for acting on the plan.
+func Calculate(ctx context.Context, c Client, config Config) (*Plan, *CrossCheck, error) {
+	load, err := awaitResult(ctx, config.pollInterval(), func(ctx context.Context) (*api.Response[types.BrokerStats], error) {
+		resp, err := c.KafkaClusterLoad(ctx, api.KafkaClusterLoadRequestWithDefaults())
+		return (*api.Response[types.BrokerStats])(resp), err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Kafka cluster load: %w", err)
+	}
+
+	if config.MaxReplicationFactor <= 0 {
+		state, err := awaitResult(ctx, config.pollInterval(), func(ctx context.Context) (*api.Response[types.KafkaClusterState], error) {
+			resp, err := c.KafkaClusterState(ctx, api.KafkaClusterStateRequestWithDefaults())
+			return (*api.Response[types.KafkaClusterState])(resp), err
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get Kafka cluster state: %w", err)
+		}
+		config.MaxReplicationFactor = maxReplicationFactor(state)
+	}
+	plan := NewPlan(load, config)
+
+	proposals, err := awaitResult(ctx, config.pollInterval(), func(ctx context.Context) (*api.Response[types.OptimizationResult], error) {
+		resp, err := c.Proposals(ctx, api.ProposalsRequestWithDefaults())
+		return (*api.Response[types.OptimizationResult])(resp), err
+	})
+	var notReady *types.NotReadyError
+	switch {
+	case errors.As(err, &notReady):
+		return plan, nil, nil
+	case err != nil:
+		return nil, nil, fmt.Errorf("failed to get optimization proposals: %w", err)
+	}
+
+	return plan, plan.CrossCheck(&proposals.Summary), nil
+}
+
+// Provision requests the brokers the plan needs from the provisioner of Cruise Control using the RIGHTSIZE
+// endpoint and returns the result reported by Cruise Control. Unlike Calculate, it changes the cluster, so it is
+// meant to be called explicitly once the plan got reviewed. It returns an error if the plan does not need more
+// brokers.
+func Provision(ctx context.Context, c Provisioner, plan *Plan, config Config) (*types.RightsizeResult, error) {
+	if plan.BrokersToAdd <= 0 {
+		return nil, fmt.Errorf("plan does not need more brokers (%+d brokers)", plan.BrokersToAdd)
+	}
+
+	req := api.RightsizeRequestWithDefaults()
+	req.NumberOfBrokersToAdd = int32(plan.BrokersToAdd)
+	result, err := awaitResult(ctx, config.pollInterval(), func(ctx context.Context) (*api.Response[types.RightsizeResult], error) {
+		resp, err := c.Rightsize(ctx, req)
+		return (*api.Response[types.RightsizeResult])(resp), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request brokers from the provisioner: %w", err)
+	}
+	return result, nil
+}
+
+// awaitResult sends the request until Cruise Control returns its result instead of its progress. The requests are
+// sent in the same session so Cruise Control returns the progress of the same user task until it finishes.
+func awaitResult[T any](ctx context.Context, interval time.Duration,
+	call func(context.Context) (*api.Response[T], error),
+) (*T, error) {
+	ctx = client.ContextWithSession(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := call(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !resp.InProgress() {
+			if resp.Result == nil {
+				return nil, errors.New("result is missing from the response")
+			}
+			return resp.Result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// maxReplicationFactor returns the highest number of replicas of the partitions of the cluster.
+func maxReplicationFactor(state *types.KafkaClusterState) int {
+	ps := state.KafkaPartitionState
+	rf := 0
+	for _, partitions := range [][]types.PartitionState{
+		ps.Offline, ps.WithOfflineReplicas, ps.UnderReplicatedPartitions, ps.UnderMinISR, ps.Other,
+	} {
+		for _, p := range partitions {
+			rf = max(rf, len(p.Replicas))
+		}
+	}
+	return rf
+}
+
+// CrossCheck is the comparison of the capacity plan with the provisioning recommendations of Cruise Control.
+type CrossCheck struct {
+	// PlanStatus is the provision status according to the capacity plan.
+	PlanStatus types.ProvisionStatus `json:"planStatus"`
+	// ServerStatus is the provision status reported by the optimizer of Cruise Control.
+	ServerStatus types.ProvisionStatus `json:"serverStatus"`
+	// ServerRecommendation is the provision recommendation reported by the optimizer of Cruise Control.
+	ServerRecommendation string `json:"serverRecommendation,omitempty"`
+	// Agrees is false if the plan contradicts the server side results.
+	Agrees bool `json:"agrees"`
+	// Discrepancies describes the differences between the plan and the server side results.
+	Discrepancies []string `json:"discrepancies,omitempty"`
+}
+
+// CrossCheck compares the plan with the provision status reported by the optimizer of Cruise Control, which is
+// optional.
+func (p *Plan) CrossCheck(summary *types.OptimizerResult) *CrossCheck {
+	c := &CrossCheck{
+		PlanStatus: p.ProvisionStatus(),
+		Agrees:     true,
+	}
+
+	if summary != nil {
+		c.ServerStatus = summary.ProvisionStatus
+		c.ServerRecommendation = summary.ProvisionRecommendation
+		switch summary.ProvisionStatus {
+		case types.ProvisionStatusRightSized, types.ProvisionStatusUnderProvisioned,
+			types.ProvisionStatusOverProvisioned:
+			if summary.ProvisionStatus != c.PlanStatus {
+				c.Agrees = false
+				c.Discrepancies = append(c.Discrepancies, fmt.Sprintf(
+					"plan estimates the cluster to be %s (%+d brokers), the optimizer reports %s",
+					c.PlanStatus, p.BrokersToAdd, summary.ProvisionStatus))
+			}
+		case types.ProvisionStatusUndecided, types.UndefinedProvisionedStatus:
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,248 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// fakeClient returns in progress responses for the first pending requests of each endpoint.
+type fakeClient struct {
+	load         *types.BrokerStats
+	state        *types.KafkaClusterState
+	summary      types.OptimizerResult
+	proposalsErr error
+	pending      int
+
+	loadRequests      int
+	stateRequests     int
+	proposalsRequests int
+	rightsizeRequests []*api.RightsizeRequest
+}
+
+func (f *fakeClient) inProgress(requests int) *types.ProgressResult {
+	if requests <= f.pending {
+		return &types.ProgressResult{}
+	}
+	return nil
+}
+
+func (f *fakeClient) KafkaClusterLoad(_ context.Context, _ *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error) { //nolint:lll
+	f.loadRequests++
+	resp := &api.KafkaClusterLoadResponse{}
+	if resp.Progress = f.inProgress(f.loadRequests); resp.Progress == nil {
+		resp.Result = f.load
+	}
+	return resp, nil
+}
+
+func (f *fakeClient) KafkaClusterState(_ context.Context, _ *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error) { //nolint:lll
+	f.stateRequests++
+	return &api.KafkaClusterStateResponse{Result: f.state}, nil
+}
+
+func (f *fakeClient) Proposals(_ context.Context, _ *api.ProposalsRequest) (*api.ProposalsResponse, error) {
+	f.proposalsRequests++
+	if f.proposalsErr != nil {
+		return nil, f.proposalsErr
+	}
+	resp := &api.ProposalsResponse{}
+	if resp.Progress = f.inProgress(f.proposalsRequests); resp.Progress == nil {
+		resp.Result = &types.OptimizationResult{Summary: f.summary}
+	}
+	return resp, nil
+}
+
+func (f *fakeClient) Rightsize(_ context.Context, r *api.RightsizeRequest) (*api.RightsizeResponse, error) {
+	f.rightsizeRequests = append(f.rightsizeRequests, r)
+	return &api.RightsizeResponse{Result: &types.RightsizeResult{
+		NumberOfBrokersToAdd: r.NumberOfBrokersToAdd,
+		ProvisionerState:     types.ProvisionerStateCompleted,
+	}}, nil
+}
+
+// lightlyLoaded returns the load of brokers spread across the racks using a tenth of their capacity.
+func lightlyLoaded(racks ...string) *types.BrokerStats {
+	stats := &types.BrokerStats{}
+	for i, rack := range racks {
+		stats.Brokers = append(stats.Brokers, types.BrokerLoadStats{
+			Broker: int32(i), Rack: rack, BrokerState: types.BrokerStateAlive,
+			NumCore: 4, CPUPct: 10,
+			DiskMB: 100, DiskCapacityMB: 1000,
+			LeaderNwInRate: 50, FollowerNwInRate: 50, NetworkInCapacity: 1000,
+			NwOutRate: 100, NetworkOutCapacity: 1000,
+		})
+	}
+	return stats
+}
+
+func replicatedState(rf int) *types.KafkaClusterState {
+	replicas := make([]int32, rf)
+	for i := range replicas {
+		replicas[i] = int32(i)
+	}
+	return &types.KafkaClusterState{KafkaPartitionState: types.KafkaPartitionState{
+		Other: []types.PartitionState{{Topic: "test", Partition: 0, Replicas: replicas}},
+	}}
+}
+
+func TestCalculate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Waits for in progress responses", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			load:    lightlyLoaded("a", "b", "c"),
+			state:   replicatedState(3),
+			summary: types.OptimizerResult{ProvisionStatus: types.ProvisionStatusRightSized},
+			pending: 2,
+		}
+		plan, check, err := Calculate(ctx, client, Config{PollInterval: time.Millisecond})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(client.loadRequests).To(Equal(3))
+		g.Expect(client.proposalsRequests).To(Equal(3))
+		g.Expect(plan.CurrentBrokers).To(Equal(3))
+		g.Expect(check).NotTo(BeNil())
+	})
+
+	t.Run("Fails if result is missing", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{state: replicatedState(3)}
+		_, _, err := Calculate(ctx, client, Config{PollInterval: time.Millisecond})
+		g.Expect(err).To(MatchError(ContainSubstring("result is missing")))
+	})
+
+	t.Run("Small healthy cluster is right-sized", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			load:    lightlyLoaded("a", "b", "c"),
+			state:   replicatedState(3),
+			summary: types.OptimizerResult{ProvisionStatus: types.ProvisionStatusRightSized},
+		}
+		plan, check, err := Calculate(ctx, client, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.RequiredBrokersByResource[types.ResourceTypeDisk.String()]).To(Equal(1))
+		g.Expect(plan.MinRequiredBrokers).To(Equal(3))
+		g.Expect(plan.RequiredBrokers).To(Equal(3))
+		g.Expect(plan.LimitingResource).To(Equal(types.ResourceTypeUndefined))
+		g.Expect(plan.ProvisionStatus()).To(Equal(types.ProvisionStatusRightSized))
+		g.Expect(check.Agrees).To(BeTrue())
+		g.Expect(client.rightsizeRequests).To(BeEmpty())
+	})
+
+	t.Run("Replication factor set in configuration", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{load: lightlyLoaded("", "", "", "")}
+		plan, _, err := Calculate(ctx, client, Config{MaxReplicationFactor: 2})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(client.stateRequests).To(BeZero())
+		g.Expect(plan.MinRequiredBrokers).To(Equal(2))
+		g.Expect(plan.BrokersToAdd).To(Equal(-2))
+	})
+
+	t.Run("Rack count is the minimum", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{load: lightlyLoaded("a", "b", "c", "d", "a"), state: replicatedState(2)}
+		plan, _, err := Calculate(ctx, client, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.MinRequiredBrokers).To(Equal(4))
+		g.Expect(plan.BrokersToAdd).To(Equal(-1))
+	})
+
+	t.Run("Under-provisioned cluster", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			load:    lightlyLoaded("a", "b", "c"),
+			state:   replicatedState(3),
+			summary: types.OptimizerResult{ProvisionStatus: types.ProvisionStatusUnderProvisioned},
+		}
+		plan, check, err := Calculate(ctx, client, Config{GrowthFactor: 10})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.BrokersToAdd).To(Equal(2))
+		g.Expect(check.Agrees).To(BeTrue())
+		g.Expect(client.rightsizeRequests).To(BeEmpty())
+	})
+
+	t.Run("Disagrees with the optimizer", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			load:  lightlyLoaded("a", "b", "c"),
+			state: replicatedState(3),
+			summary: types.OptimizerResult{
+				ProvisionStatus:         types.ProvisionStatusOverProvisioned,
+				ProvisionRecommendation: "remove 1 broker",
+			},
+		}
+		_, check, err := Calculate(ctx, client, Config{GrowthFactor: 10})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(check.Agrees).To(BeFalse())
+		g.Expect(check.ServerRecommendation).To(Equal("remove 1 broker"))
+		g.Expect(check.Discrepancies).To(HaveLen(1))
+	})
+
+	t.Run("Cross-check is omitted if Cruise Control is not ready", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			load:         lightlyLoaded("a", "b", "c"),
+			state:        replicatedState(3),
+			proposalsErr: &types.NotReadyError{CruiseControlError: &types.CruiseControlError{}},
+		}
+		plan, check, err := Calculate(ctx, client, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan).NotTo(BeNil())
+		g.Expect(check).To(BeNil())
+	})
+}
+
+func TestProvision(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Requests brokers from the provisioner", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{}
+		result, err := Provision(ctx, client, &Plan{BrokersToAdd: 2}, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(client.rightsizeRequests).To(HaveLen(1))
+		g.Expect(client.rightsizeRequests[0].NumberOfBrokersToAdd).To(Equal(int32(2)))
+		g.Expect(result.ProvisionerState).To(Equal(types.ProvisionerStateCompleted))
+	})
+
+	t.Run("Plan without new brokers", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{}
+		_, err := Provision(ctx, client, &Plan{BrokersToAdd: -1}, Config{})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(client.rightsizeRequests).To(BeEmpty())
+	})
+}
@@ -0,0 +1,357 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultCPUTargetUtilization     = 0.7
+	DefaultDiskTargetUtilization    = 0.8
+	DefaultNetworkTargetUtilization = 0.8
+	DefaultPollInterval             = 5 * time.Second
+)
+
+// Resources lists the resources considered during capacity planning in the order they are reported.
+var Resources = []types.ResourceType{
+	types.ResourceTypeCPU,
+	types.ResourceTypeDisk,
+	types.ResourceTypeNetworkIn,
+	types.ResourceTypeNetworkOut,
+}
+
+// BrokerProfile describes the capacity of a broker.
+type BrokerProfile struct {
+	NumCore        float64 `json:"numCore"`
+	DiskCapacityMB float64 `json:"diskCapacityMB"`
+	// NetworkInCapacity in KB/s.
+	NetworkInCapacity float64 `json:"networkInCapacity"`
+	// NetworkOutCapacity in KB/s.
+	NetworkOutCapacity float64 `json:"networkOutCapacity"`
+}
+
+// Capacity returns the capacity of the broker for the resource. CPU capacity is measured in cores.
+func (p BrokerProfile) Capacity(r types.ResourceType) float64 {
+	switch r {
+	case types.ResourceTypeCPU:
+		return p.NumCore
+	case types.ResourceTypeDisk:
+		return p.DiskCapacityMB
+	case types.ResourceTypeNetworkIn:
+		return p.NetworkInCapacity
+	case types.ResourceTypeNetworkOut:
+		return p.NetworkOutCapacity
+	case types.ResourceTypeUndefined:
+		fallthrough
+	default:
+		return 0
+	}
+}
+
+// Config contains the configuration parameters of capacity planning.
+type Config struct {
+	// TargetUtilization is the maximum utilization (between 0 and 1) of each resource the cluster should stay under.
+	// The Default*TargetUtilization values are used for resources without a target.
+	TargetUtilization map[types.ResourceType]float64
+	// GrowthFactor is applied to the current usage to project the future utilization, e.g. 1.5 for 50% growth.
+	// The current usage is used if not set.
+	GrowthFactor float64
+	// BrokerProfile is the capacity of the brokers to be added to the cluster.
+	// The average capacity of the alive brokers is used if not set.
+	BrokerProfile *BrokerProfile
+	// MaxReplicationFactor is the highest replication factor of the topics. The cluster needs at least as many
+	// brokers to host every replica of the partitions. Calculate retrieves it from Cruise Control if not set.
+	MaxReplicationFactor int
+	// PollInterval is the interval between the requests waiting for in progress responses of Cruise Control.
+	// DefaultPollInterval is used if not set.
+	PollInterval time.Duration
+}
+
+func (c Config) target(r types.ResourceType) float64 {
+	if t, ok := c.TargetUtilization[r]; ok && t > 0 {
+		return t
+	}
+	if r == types.ResourceTypeCPU {
+		return DefaultCPUTargetUtilization
+	}
+	if r == types.ResourceTypeDisk {
+		return DefaultDiskTargetUtilization
+	}
+	return DefaultNetworkTargetUtilization
+}
+
+func (c Config) pollInterval() time.Duration {
+	if c.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return c.PollInterval
+}
+
+func (c Config) growthFactor() float64 {
+	if c.GrowthFactor <= 0 {
+		return 1
+	}
+	return c.GrowthFactor
+}
+
+// ResourceUsage is the usage and the headroom of a resource.
+type ResourceUsage struct {
+	Resource types.ResourceType `json:"resource"`
+	Used     float64            `json:"used"`
+	Capacity float64            `json:"capacity"`
+	// Utilization is the ratio of the used and the total capacity.
+	Utilization float64 `json:"utilization"`
+	// ProjectedUtilization is the utilization after applying the growth factor.
+	ProjectedUtilization float64 `json:"projectedUtilization"`
+	// Target is the utilization to stay under.
+	Target float64 `json:"target"`
+	// Headroom is the capacity left until the target utilization is reached after applying the growth factor.
+	// It is negative if the target is exceeded.
+	Headroom float64 `json:"headroom"`
+}
+
+// Exceeded returns true if the projected utilization is above the target utilization.
+func (u ResourceUsage) Exceeded() bool {
+	return u.ProjectedUtilization > u.Target
+}
+
+func newResourceUsage(r types.ResourceType, used, capacity, growth, target float64) ResourceUsage {
+	u := ResourceUsage{
+		Resource: r,
+		Used:     used,
+		Capacity: capacity,
+		Target:   target,
+		Headroom: capacity*target - used*growth,
+	}
+	if capacity > 0 {
+		u.Utilization = used / capacity
+		u.ProjectedUtilization = used * growth / capacity
+	}
+	return u
+}
+
+// BrokerHeadroom is the headroom of a single broker.
+type BrokerHeadroom struct {
+	Broker    int32             `json:"broker"`
+	Host      string            `json:"host"`
+	Rack      string            `json:"rack"`
+	State     types.BrokerState `json:"state"`
+	Resources []ResourceUsage   `json:"resources"`
+}
+
+// GroupHeadroom is the aggregated headroom of the brokers of a rack or a host.
+type GroupHeadroom struct {
+	Name      string          `json:"name"`
+	Brokers   []int32         `json:"brokers,omitempty"`
+	Resources []ResourceUsage `json:"resources"`
+}
+
+// Plan is the result of capacity planning.
+type Plan struct {
+	GrowthFactor float64          `json:"growthFactor"`
+	Profile      BrokerProfile    `json:"profile"`
+	Brokers      []BrokerHeadroom `json:"brokers"`
+	Racks        []GroupHeadroom  `json:"racks"`
+	Hosts        []GroupHeadroom  `json:"hosts"`
+	Cluster      []ResourceUsage  `json:"cluster"`
+	// CurrentBrokers is the number of alive brokers.
+	CurrentBrokers int `json:"currentBrokers"`
+	// RequiredBrokersByResource is the number of brokers with the profile needed to stay under the target
+	// utilization of each resource.
+	RequiredBrokersByResource map[string]int `json:"requiredBrokersByResource"`
+	// MinRequiredBrokers is the number of brokers needed regardless of the load: every replica of the partitions
+	// is hosted by a separate broker and each rack keeps at least one broker.
+	MinRequiredBrokers int `json:"minRequiredBrokers"`
+	// RequiredBrokers is the number of brokers needed to stay under the target utilization of all resources.
+	// It is never below MinRequiredBrokers.
+	RequiredBrokers int `json:"requiredBrokers"`
+	// BrokersToAdd is the difference of the required and the current brokers. It is negative if the cluster
+	// is over-provisioned.
+	BrokersToAdd int `json:"brokersToAdd"`
+	// LimitingResource is the resource requiring the most brokers. It is undefined if the required brokers
+	// are determined by MinRequiredBrokers.
+	LimitingResource types.ResourceType `json:"limitingResource"`
+}
+
+// ProvisionStatus returns the provision status of the cluster according to the plan.
+func (p *Plan) ProvisionStatus() types.ProvisionStatus {
+	switch {
+	case p.BrokersToAdd > 0:
+		return types.ProvisionStatusUnderProvisioned
+	case p.BrokersToAdd < 0:
+		return types.ProvisionStatusOverProvisioned
+	default:
+		return types.ProvisionStatusRightSized
+	}
+}
+
+// brokerUsage returns the used and the total capacity of the resource of the broker.
+func brokerUsage(b types.BrokerLoadStats, r types.ResourceType) (float64, float64) {
+	switch r {
+	case types.ResourceTypeCPU:
+		return b.CPUPct / 100 * b.NumCore, b.NumCore //nolint:gomnd
+	case types.ResourceTypeDisk:
+		return b.DiskMB, b.DiskCapacityMB
+	case types.ResourceTypeNetworkIn:
+		return b.LeaderNwInRate + b.FollowerNwInRate, b.NetworkInCapacity
+	case types.ResourceTypeNetworkOut:
+		return b.NwOutRate, b.NetworkOutCapacity
+	case types.ResourceTypeUndefined:
+		fallthrough
+	default:
+		return 0, 0
+	}
+}
+
+// hostUsage returns the used and the total capacity of the resource of the host.
+func hostUsage(h types.HostLoadStats, r types.ResourceType) (float64, float64) {
+	return brokerUsage(types.BrokerLoadStats{
+		FollowerNwInRate:   h.FollowerNwInRate,
+		NwOutRate:          h.NwOutRate,
+		NumCore:            h.NumCore,
+		CPUPct:             h.CPUPct,
+		NetworkInCapacity:  h.NetworkInCapacity,
+		DiskCapacityMB:     h.DiskCapacityMB,
+		DiskMB:             h.DiskMB,
+		NetworkOutCapacity: h.NetworkOutCapacity,
+		LeaderNwInRate:     h.LeaderNwInRate,
+	}, r)
+}
+
+// NewPlan calculates the capacity plan of the cluster from its load. Dead brokers are not considered as
+// their capacity is not available for the cluster.
+func NewPlan(stats *types.BrokerStats, config Config) *Plan {
+	growth := config.growthFactor()
+	plan := &Plan{
+		GrowthFactor:              growth,
+		Brokers:                   make([]BrokerHeadroom, 0, len(stats.Brokers)),
+		Racks:                     make([]GroupHeadroom, 0),
+		Hosts:                     make([]GroupHeadroom, 0, len(stats.Hosts)),
+		Cluster:                   make([]ResourceUsage, 0, len(Resources)),
+		RequiredBrokersByResource: make(map[string]int, len(Resources)),
+	}
+
+	type totals struct {
+		brokers  []int32
+		used     map[types.ResourceType]float64
+		capacity map[types.ResourceType]float64
+	}
+	newTotals := func() *totals {
+		return &totals{
+			used:     make(map[types.ResourceType]float64),
+			capacity: make(map[types.ResourceType]float64),
+		}
+	}
+	cluster := newTotals()
+	racks := make(map[string]*totals)
+
+	for _, b := range stats.Brokers {
+		if b.BrokerState == types.BrokerStateDead {
+			continue
+		}
+		plan.CurrentBrokers++
+
+		rack := racks[b.Rack]
+		if rack == nil {
+			rack = newTotals()
+			racks[b.Rack] = rack
+		}
+		rack.brokers = append(rack.brokers, b.Broker)
+
+		h := BrokerHeadroom{
+			Broker:    b.Broker,
+			Host:      b.Host,
+			Rack:      b.Rack,
+			State:     b.BrokerState,
+			Resources: make([]ResourceUsage, 0, len(Resources)),
+		}
+		for _, r := range Resources {
+			used, capacity := brokerUsage(b, r)
+			h.Resources = append(h.Resources, newResourceUsage(r, used, capacity, growth, config.target(r)))
+			for _, t := range []*totals{cluster, rack} {
+				t.used[r] += used
+				t.capacity[r] += capacity
+			}
+		}
+		plan.Brokers = append(plan.Brokers, h)
+	}
+	sort.Slice(plan.Brokers, func(i, j int) bool { return plan.Brokers[i].Broker < plan.Brokers[j].Broker })
+
+	for name, t := range racks {
+		g := GroupHeadroom{Name: name, Brokers: t.brokers, Resources: make([]ResourceUsage, 0, len(Resources))}
+		for _, r := range Resources {
+			g.Resources = append(g.Resources, newResourceUsage(r, t.used[r], t.capacity[r], growth, config.target(r)))
+		}
+		plan.Racks = append(plan.Racks, g)
+	}
+	sort.Slice(plan.Racks, func(i, j int) bool { return plan.Racks[i].Name < plan.Racks[j].Name })
+
+	for _, host := range stats.Hosts {
+		g := GroupHeadroom{Name: host.Host, Resources: make([]ResourceUsage, 0, len(Resources))}
+		for _, r := range Resources {
+			used, capacity := hostUsage(host, r)
+			g.Resources = append(g.Resources, newResourceUsage(r, used, capacity, growth, config.target(r)))
+		}
+		plan.Hosts = append(plan.Hosts, g)
+	}
+	sort.Slice(plan.Hosts, func(i, j int) bool { return plan.Hosts[i].Name < plan.Hosts[j].Name })
+
+	for _, r := range Resources {
+		plan.Cluster = append(plan.Cluster,
+			newResourceUsage(r, cluster.used[r], cluster.capacity[r], growth, config.target(r)))
+	}
+
+	if config.BrokerProfile != nil {
+		plan.Profile = *config.BrokerProfile
+	} else if plan.CurrentBrokers > 0 {
+		n := float64(plan.CurrentBrokers)
+		plan.Profile = BrokerProfile{
+			NumCore:            cluster.capacity[types.ResourceTypeCPU] / n,
+			DiskCapacityMB:     cluster.capacity[types.ResourceTypeDisk] / n,
+			NetworkInCapacity:  cluster.capacity[types.ResourceTypeNetworkIn] / n,
+			NetworkOutCapacity: cluster.capacity[types.ResourceTypeNetworkOut] / n,
+		}
+	}
+
+	rackCount := len(racks)
+	if _, ok := racks[""]; ok {
+		// Brokers without rack information are not placed in a rack.
+		rackCount--
+	}
+	plan.MinRequiredBrokers = max(1, config.MaxReplicationFactor, rackCount)
+	plan.RequiredBrokers = plan.MinRequiredBrokers
+	for _, r := range Resources {
+		capacity := plan.Profile.Capacity(r) * config.target(r)
+		if capacity <= 0 {
+			continue
+		}
+		required := int(math.Ceil(cluster.used[r] * growth / capacity))
+		plan.RequiredBrokersByResource[r.String()] = required
+		if required > plan.RequiredBrokers {
+			plan.RequiredBrokers = required
+			plan.LimitingResource = r
+		}
+	}
+	plan.BrokersToAdd = plan.RequiredBrokers - plan.CurrentBrokers
+
+	return plan
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestNewPlan(t *testing.T) {
+	stats := &types.BrokerStats{
+		Brokers: []types.BrokerLoadStats{
+			{
+				Broker: 0, Rack: "a", BrokerState: types.BrokerStateAlive,
+				NumCore: 4, CPUPct: 25,
+				DiskMB: 600, DiskCapacityMB: 1000,
+				LeaderNwInRate: 100, FollowerNwInRate: 100, NetworkInCapacity: 1000,
+				NwOutRate: 200, NetworkOutCapacity: 1000,
+			},
+			{
+				Broker: 1, Rack: "b", BrokerState: types.BrokerStateAlive,
+				NumCore: 4, CPUPct: 25,
+				DiskMB: 600, DiskCapacityMB: 1000,
+				LeaderNwInRate: 100, FollowerNwInRate: 100, NetworkInCapacity: 1000,
+				NwOutRate: 200, NetworkOutCapacity: 1000,
+			},
+			{Broker: 2, Rack: "b", BrokerState: types.BrokerStateDead, NumCore: 4, DiskCapacityMB: 1000},
+		},
+	}
+
+	t.Run("Current usage", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan := NewPlan(stats, Config{})
+
+		g.Expect(plan.CurrentBrokers).To(Equal(2))
+		g.Expect(plan.Brokers).To(HaveLen(2))
+		g.Expect(plan.Racks).To(HaveLen(2))
+		g.Expect(plan.Brokers[0].Resources[1].Utilization).To(BeNumerically("~", 0.6))
+		g.Expect(plan.Brokers[0].Resources[1].Headroom).To(BeNumerically("~", 200))
+		g.Expect(plan.RequiredBrokers).To(Equal(2))
+		g.Expect(plan.ProvisionStatus()).To(Equal(types.ProvisionStatusRightSized))
+	})
+
+	t.Run("Projected growth", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan := NewPlan(stats, Config{GrowthFactor: 2})
+
+		g.Expect(plan.Cluster[1].ProjectedUtilization).To(BeNumerically("~", 1.2))
+		g.Expect(plan.Cluster[1].Exceeded()).To(BeTrue())
+		g.Expect(plan.LimitingResource).To(Equal(types.ResourceTypeDisk))
+		g.Expect(plan.RequiredBrokers).To(Equal(3))
+		g.Expect(plan.BrokersToAdd).To(Equal(1))
+
+		check := plan.CrossCheck(&types.OptimizerResult{ProvisionStatus: types.ProvisionStatusRightSized})
+		g.Expect(check.Agrees).To(BeFalse())
+		g.Expect(check.Discrepancies).To(HaveLen(1))
+	})
+}
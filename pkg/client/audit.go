@@ -0,0 +1,52 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// audit records the mutating request in the audit log.
+func (c Client) audit(ctx context.Context, req interface{}, resp types.APIResponse, e types.APIEndpoint, m string,
+	start time.Time, err error,
+) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	params := url.Values{}
+	if r, mErr := MarshalRequest(req); mErr == nil && r.URL != nil {
+		params = r.URL.Query()
+	}
+	if reason, ok := ReasonFromContext(ctx); ok {
+		params.Set(ReasonQueryParam, reason)
+	}
+
+	record := audit.NewRecord(c.cluster, e, m, params, resp, err)
+	record.Time = start
+	record.Duration = time.Since(start)
+
+	// The request context might already be cancelled, which must not prevent recording the request.
+	if aErr := c.auditLog.Append(context.WithoutCancel(ctx), record); aErr != nil {
+		log.Error(aErr, "failed to record request in audit log", "endpoint", e)
+	}
+}
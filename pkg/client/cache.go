@@ -0,0 +1,248 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// maxCacheFetchTimeout bounds the shared request of the callers waiting for the same response if the caller
+// sending it has no earlier deadline.
+const maxCacheFetchTimeout = 5 * time.Minute
+
+// DefaultResponseCacheTTLs returns cache TTLs for the read-only endpoints which are expensive to compute
+// and are often requested by multiple callers.
+func DefaultResponseCacheTTLs() map[types.APIEndpoint]time.Duration {
+	return map[types.APIEndpoint]time.Duration{
+		api.EndpointState:              5 * time.Second,
+		api.EndpointKafkaClusterState:  10 * time.Second,
+		api.EndpointKafkaClusterLoad:   30 * time.Second,
+		api.EndpointKafkaPartitionLoad: 30 * time.Second,
+		api.EndpointProposals:          30 * time.Second,
+	}
+}
+
+// cachedResponse is a successful HTTP response with its body read into memory.
+type cachedResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+	request    *http.Request
+	expires    time.Time
+}
+
+// response returns a new HTTP response with the content of the cached response.
+func (r *cachedResponse) response() *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.statusCode, http.StatusText(r.statusCode)),
+		StatusCode:    r.statusCode,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       r.request,
+	}
+}
+
+// cacheCall is an in-flight request shared by the callers requesting the same response.
+type cacheCall struct {
+	done    chan struct{}
+	resp    *cachedResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// responseCache is a read-through cache of the responses of read-only endpoints which coalesces concurrent
+// identical requests into a single one.
+type responseCache struct {
+	mu         sync.Mutex
+	ttls       map[types.APIEndpoint]time.Duration
+	entries    map[string]*cachedResponse
+	calls      map[string]*cacheCall
+	generation uint64
+}
+
+func newResponseCache(ttls map[types.APIEndpoint]time.Duration) *responseCache {
+	c := &responseCache{
+		ttls:    make(map[types.APIEndpoint]time.Duration, len(ttls)),
+		entries: make(map[string]*cachedResponse),
+		calls:   make(map[string]*cacheCall),
+	}
+	for e, ttl := range ttls {
+		if ttl > 0 {
+			c.ttls[e] = ttl
+		}
+	}
+	return c
+}
+
+func (c *responseCache) ttl(e types.APIEndpoint) (time.Duration, bool) {
+	ttl, ok := c.ttls[e]
+	return ttl, ok
+}
+
+// get returns the cached response for the key or calls fetch to get it. Concurrent callers with the same key
+// wait for the same fetch and get its result, including its error. The fetch is not cancelled by the caller which
+// started it giving up, so it does not fail the others waiting for the same response, but it is cancelled once every
+// caller gave up. It is bound by the deadline of the caller which started it, or maxCacheFetchTimeout at most.
+func (c *responseCache) get(ctx context.Context, key string, ttl time.Duration,
+	fetch func(ctx context.Context) (*cachedResponse, error),
+) (*http.Response, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		log.V(1).Info("using cached response", "key", key)
+		return entry.response(), nil
+	}
+	call, ok := c.calls[key]
+	if ok {
+		log.V(1).Info("waiting for in-flight request", "key", key)
+		call.waiters++
+	} else {
+		timeout := maxCacheFetchTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = min(timeout, time.Until(deadline))
+		}
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		call = &cacheCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		c.calls[key] = call
+		go c.fetch(fetchCtx, key, ttl, c.generation, call, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		c.leave(key, call)
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.resp.response(), nil
+}
+
+// fetch calls fetch for the in-flight request and caches its successful response unless the cache got
+// invalidated in the meantime.
+func (c *responseCache) fetch(ctx context.Context, key string, ttl time.Duration, generation uint64, call *cacheCall,
+	fetch func(ctx context.Context) (*cachedResponse, error),
+) {
+	call.resp, call.err = fetch(ctx)
+	call.cancel()
+
+	c.mu.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	// Responses of requests which were in flight while the cache got invalidated might be stale.
+	if call.err == nil && call.resp.statusCode == http.StatusOK && generation == c.generation {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		call.resp.expires = now.Add(ttl)
+		c.entries[key] = call.resp
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// leave is called by a caller giving up waiting for the in-flight request. The request is cancelled if no other caller
+// is waiting for it, so that callers requesting the same response later send a new one.
+func (c *responseCache) leave(key string, call *cacheCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// invalidate drops every cached response.
+func (c *responseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cachedResponse)
+	c.generation++
+}
+
+// cacheKey returns the key of the request made to the endpoint with the provided parameters and response format.
+func cacheKey(ctx context.Context, e types.APIEndpoint, mimeType string, r *http.Request) string {
+	key := []string{e.String(), mimeType, r.URL.RawQuery}
+	if reason, ok := ReasonFromContext(ctx); ok {
+		key = append(key, reason)
+	}
+	return strings.Join(key, " ")
+}
+
+// InvalidateCache drops every cached response of the client.
+func (c *Client) InvalidateCache() {
+	c.cache.invalidate()
+}
+
+// sendCached returns the response of the request from the cache or sends the request and caches its response.
+// Requests pinned to a server must not use it as the cached response might have been returned by another server.
+func (c Client) sendCached(ctx context.Context, e types.APIEndpoint, key string, ttl time.Duration, r *http.Request,
+	opts ...RequestOptions,
+) (*http.Response, error) {
+	return c.cache.get(ctx, key, ttl, func(ctx context.Context) (*cachedResponse, error) {
+		release, err := c.limiters.acquire(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		// The request is shared by every caller waiting for the response, so it must not be cancelled by the
+		// context of the caller which happened to send it.
+		httpResp, err := c.send(ctx, r, append(opts, WithContext(ctx))...)
+		if err != nil {
+			return nil, err
+		}
+		defer httpResp.Body.Close()
+
+		body, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		return &cachedResponse{
+			statusCode: httpResp.StatusCode,
+			header:     httpResp.Header,
+			body:       body,
+			request:    httpResp.Request,
+		}, nil
+	})
+}
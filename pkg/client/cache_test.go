@@ -0,0 +1,252 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestResponseCache(t *testing.T) {
+	newServer := func(t *testing.T) *fakeCruiseControl {
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		server.handle(api.EndpointStopProposalExecution, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, "", `{"message": "Proposal execution stopped."}`)
+		})
+		return server
+	}
+	newClient := func(t *testing.T, ttl time.Duration, urls ...string) *Client {
+		return newTestClient(t, &Config{
+			ServerURLs:        urls,
+			ResponseCacheTTLs: map[types.APIEndpoint]time.Duration{api.EndpointState: ttl},
+		})
+	}
+	state := func(ctx context.Context, c *Client) error {
+		_, err := c.State(ctx, api.StateRequestWithDefaults())
+		return err
+	}
+	// waiters returns the number of callers waiting for in-flight requests.
+	waiters := func(c *Client) int {
+		c.cache.mu.Lock()
+		defer c.cache.mu.Unlock()
+		n := 0
+		for _, call := range c.cache.calls {
+			n += call.waiters
+		}
+		return n
+	}
+
+	t.Run("Responses are cached until the TTL expires", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newClient(t, 200*time.Millisecond, server.URL())
+
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+
+		time.Sleep(250 * time.Millisecond)
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+
+	t.Run("Requests with different parameters are not shared", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newClient(t, time.Minute, server.URL())
+
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		req := api.StateRequestWithDefaults()
+		req.Substates = []types.Substate{types.SubstateExecutor}
+		_, err := c.State(context.Background(), req)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+
+	t.Run("Failed responses are not cached", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusInternalServerError, "", `{"errorMessage": "failed"}`)
+		})
+		c := newClient(t, time.Minute, server.URL())
+
+		g.Expect(state(context.Background(), c)).NotTo(Succeed())
+		g.Expect(state(context.Background(), c)).NotTo(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+
+	t.Run("Mutating requests invalidate the cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newClient(t, time.Minute, server.URL())
+
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		_, err := c.StopProposalExecution(context.Background(), api.StopProposalExecutionRequestWithDefaults())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+
+		c.InvalidateCache()
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(3))
+	})
+
+	t.Run("Concurrent requests are coalesced", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		release := make(chan struct{})
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			<-release
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newClient(t, time.Minute, server.URL())
+
+		const callers = 5
+		errs := make(chan error, callers)
+		for i := 0; i < callers; i++ {
+			go func() {
+				errs <- state(context.Background(), c)
+			}()
+		}
+		g.Eventually(func() int { return server.count(api.EndpointState) }).Should(Equal(1))
+		close(release)
+		for i := 0; i < callers; i++ {
+			g.Expect(<-errs).To(Succeed())
+		}
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+
+	t.Run("Cancelled caller does not fail the others", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		release := make(chan struct{})
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			<-release
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newClient(t, time.Minute, server.URL())
+
+		// The first caller sends the shared request and gives up while it is in flight.
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan error, 1)
+		go func() {
+			first <- state(ctx, c)
+		}()
+		g.Eventually(func() int { return server.count(api.EndpointState) }).Should(Equal(1))
+
+		var wg sync.WaitGroup
+		var err error
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err = state(context.Background(), c)
+		}()
+		g.Eventually(func() int { return waiters(c) }).Should(Equal(2))
+
+		cancel()
+		g.Expect(errors.Is(<-first, context.Canceled)).To(BeTrue())
+
+		close(release)
+		wg.Wait()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+
+	t.Run("Request is cancelled once every caller gave up", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		cancelled := make(chan struct{})
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, r *http.Request) {
+			if server.count(api.EndpointState) == 1 {
+				<-r.Context().Done()
+				close(cancelled)
+				return
+			}
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newClient(t, time.Minute, server.URL())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan error, 1)
+		go func() {
+			first <- state(ctx, c)
+		}()
+		g.Eventually(func() int { return server.count(api.EndpointState) }).Should(Equal(1))
+
+		cancel()
+		g.Expect(errors.Is(<-first, context.Canceled)).To(BeTrue())
+		g.Eventually(cancelled).Should(BeClosed())
+
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+
+	t.Run("Request is bound by the deadline of the caller sending it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newTestClient(t, &Config{
+			ServerURLs:        []string{server.URL()},
+			ResponseCacheTTLs: map[types.APIEndpoint]time.Duration{api.EndpointState: time.Millisecond},
+			EndpointLimits:    map[types.APIEndpoint]EndpointLimit{api.EndpointState: {Rate: 1}},
+		})
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		time.Sleep(2 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := state(ctx, c)
+		g.Expect(errors.Is(err, ErrLimitWaitExceedsDeadline)).To(BeTrue())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+
+	t.Run("Requests pinned to a server bypass the cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		first := newServer(t)
+		second := newServer(t)
+		c := newClient(t, time.Minute, first.URL(), second.URL())
+
+		g.Expect(state(context.Background(), c)).To(Succeed())
+		g.Expect(first.count(api.EndpointState)).To(Equal(1))
+
+		statuses := c.CheckServers(context.Background())
+		g.Expect(statuses).To(HaveLen(2))
+		g.Expect(first.count(api.EndpointState)).To(Equal(2))
+		g.Expect(second.count(api.EndpointState)).To(Equal(1))
+	})
+}
@@ -0,0 +1,32 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Call sends the request r to the endpoint e and decodes a successful response into a result of type T.
+// It allows calling endpoints (or endpoint variants) which have no dedicated method on the Client while still
+// getting the typed response handling of the generated methods.
+func Call[T any](ctx context.Context, c *Client, e types.APIEndpoint, r interface{}) (*api.Response[T], error) {
+	resp := &api.Response[T]{}
+	return resp, c.request(ctx, r, resp, e, endpointMethod(e))
+}
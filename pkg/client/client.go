@@ -17,16 +17,17 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
-	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
 	"github.com/banzaicloud/go-cruise-control/pkg/types"
 )
 
@@ -37,25 +38,35 @@ const (
 
 type Client struct {
 	httpClient *http.Client
-	url        *url.URL
+	servers    *serverPool
 	auth       AuthInfo
 	userAgent  string
+
+	unsupportedParams UnsupportedParamsPolicy
+	compatibility     CompatibilityTable
+	version           *serverVersionCache
+
+	strictDecoding      bool
+	responseSchemaCheck bool
+	schemas             *schemaCache
+
+	limiters *endpointLimiters
+	cache    *responseCache
+
+	cluster  string
+	auditLog audit.Store
 }
 
 func (c Client) String() string {
-	return fmt.Sprintf("CruiseControlClient\n\turl: %s\n\tuseragent: %s\n", c.url, c.userAgent)
+	return fmt.Sprintf("CruiseControlClient\n\turl: %s\n\tuseragent: %s\n", c.servers.primary(), c.userAgent)
 }
 
 func (c Client) send(ctx context.Context, req *http.Request, opts ...RequestOptions) (*http.Response, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
 	opts = append(opts, []RequestOptions{
-		WithServerURL(c.url),
 		WithAuthInfo(c.auth),
 		WithUserAgent(c.userAgent),
-		WithAcceptJSON(),
-		WithContentTypeJSON(),
-		WithJSONQuery(),
 	}...)
 
 	for _, o := range opts {
@@ -63,16 +74,98 @@ func (c Client) send(ctx context.Context, req *http.Request, opts ...RequestOpti
 			return nil, fmt.Errorf("failed to apply option(s) to HTTP request: %w", err)
 		}
 	}
-	log.V(-1).Info("sending request", "url", req.URL, "method", req.Method)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		err = fmt.Errorf("sending HTTP request failed: %w", err)
+	candidates := c.servers.candidates(time.Now())
+	if server, ok := c.servers.pinned(ctx, req); ok {
+		candidates = []int{server}
+	}
+	// Requests with a body which cannot be re-read are only sent to the first server.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		candidates = candidates[:1]
+	}
+
+	var resp *http.Response
+	var err error
+	for n, server := range candidates {
+		last := n == len(candidates)-1
+
+		r := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("failed to copy HTTP request body: %w", err)
+			}
+		}
+		if err = WithServerURL(c.servers.urls[server]).apply(r); err != nil {
+			return nil, fmt.Errorf("failed to apply option(s) to HTTP request: %w", err)
+		}
+		session, hasSession := SessionFromContext(ctx)
+		if hasSession {
+			session.apply(r)
+		}
+		log.V(-1).Info("sending request", "url", r.URL, "method", r.Method)
+
+		resp, err = c.httpClient.Do(r)
+		if err != nil {
+			err = &types.TransportError{Method: r.Method, URL: r.URL.String(), Err: err}
+			c.servers.markFailed(server, err)
+			if last || req.Context().Err() != nil || !canFailover(r.Method, err) {
+				return nil, err
+			}
+			log.V(0).Info("failing over to next server", "url", r.URL, "error", err)
+			continue
+		}
+
+		switch {
+		case shouldFailover(resp):
+			c.servers.markFailed(server, fmt.Errorf("server responded with status %d", resp.StatusCode))
+			if !last {
+				log.V(0).Info("failing over to next server", "url", r.URL, "status", resp.StatusCode)
+				_ = resp.Body.Close()
+				continue
+			}
+		case serverUnavailable(resp):
+			c.servers.markFailed(server, fmt.Errorf("server responded with status %d", resp.StatusCode))
+		default:
+			c.servers.markHealthy(server)
+		}
+		if id := resp.Header.Get(types.UserTaskIDHTTPHeader); id != "" {
+			c.servers.setOwner(id, server)
+		}
+		if hasSession {
+			session.update(r.URL, resp)
+		}
+		break
 	}
 	return resp, err
 }
 
 func (c Client) request(ctx context.Context, req interface{}, resp types.APIResponse, e types.APIEndpoint, m string) error {
+	return c.do(ctx, req, resp, e, m, MIMETypeJSON,
+		WithAcceptJSON(),
+		WithContentTypeJSON(),
+		WithJSONQuery(),
+	)
+}
+
+// do sends the request to the endpoint and converts the HTTP response to API response. The response is rejected
+// if its content type does not match the expected MIME type. Mutating requests are recorded in the audit log
+// if it is configured.
+func (c Client) do(ctx context.Context, req interface{}, resp types.APIResponse, e types.APIEndpoint, m string,
+	mimeType string, formatOpts ...RequestOptions,
+) error {
+	if c.auditLog == nil || m == http.MethodGet {
+		return c.exchange(ctx, req, resp, e, m, mimeType, formatOpts...)
+	}
+
+	start := time.Now()
+	err := c.exchange(ctx, req, resp, e, m, mimeType, formatOpts...)
+	c.audit(ctx, req, resp, e, m, start, err)
+	return err
+}
+
+func (c Client) exchange(ctx context.Context, req interface{}, resp types.APIResponse, e types.APIEndpoint, m string,
+	mimeType string, formatOpts ...RequestOptions,
+) error {
 	log := logr.FromContextOrDiscard(ctx)
 
 	r, err := MarshalRequest(req)
@@ -80,17 +173,64 @@ func (c Client) request(ctx context.Context, req interface{}, resp types.APIResp
 		return err
 	}
 
+	// The key is computed before unsupported parameters are dropped to match the one used by ResponseSchema.
+	schema := schemaKey(e, r)
+	if err = c.checkCompatibility(ctx, r, e); err != nil {
+		return err
+	}
+
 	opts := []RequestOptions{
 		WithEndpoint(e),
 		WithMethod(m),
 		WithContext(ctx),
 	}
+	opts = append(opts, formatOpts...)
+	if c.responseSchemaCheck && mimeType == MIMETypeJSON {
+		opts = append(opts, WithResponseSchemaQuery())
+	}
 
 	if _, ok := req.(types.RequestReasoner); ok {
 		opts = append(opts, WithReasonFromContext(ctx))
 	}
 
-	httpResp, err := c.send(ctx, r, opts...)
+	if m != http.MethodGet {
+		// Mutating requests are likely to change the responses of read-only endpoints.
+		defer c.cache.invalidate()
+	}
+
+	session, hasSession := SessionFromContext(ctx)
+
+	_, pinned := c.servers.pinned(ctx, r)
+
+	var httpResp *http.Response
+	if ttl, ok := c.cache.ttl(e); ok && m == http.MethodGet && !hasSession && !pinned {
+		httpResp, err = c.sendCached(ctx, e, cacheKey(ctx, e, mimeType, r), ttl, r, opts...)
+	} else {
+		release, lErr := c.limiters.acquire(ctx, e)
+		if lErr != nil {
+			return lErr
+		}
+		defer release()
+
+		var retry *http.Request
+		var key string
+		if hasSession {
+			retry = r.Clone(ctx)
+			key = cacheKey(ctx, e, mimeType, r)
+		}
+		httpResp, err = c.send(ctx, r, opts...)
+		if err == nil && hasSession && session.stale(key, httpResp) {
+			// Cruise Control returned the response of an earlier request of the session, so the request is sent
+			// again in a new session.
+			log.V(0).Info("discarding stale response of session", "url", httpResp.Request.URL,
+				"user_task_id", httpResp.Header.Get(types.UserTaskIDHTTPHeader))
+			_ = httpResp.Body.Close()
+			session.Reset()
+			if httpResp, err = c.send(ctx, retry, opts...); err == nil {
+				session.stale(key, httpResp)
+			}
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -104,15 +244,29 @@ func (c Client) request(ctx context.Context, req interface{}, resp types.APIResp
 	log.V(0).Info("got response for request", "url", httpResp.Request.URL,
 		"status", httpResp.StatusCode)
 
+	c.version.update(httpResp.Header.Get(types.CruiseControlVersionHTTPHeader))
+
 	contentType := parseContentType(httpResp.Header.Get(HTTPHeaderContentType))
-	if contentType.MIMEType != MIMETypeJSON && contentType.ChartSet != ChartSetUTF8 {
+	if !contentType.Matches(mimeType) {
 		return errors.Errorf("content type mismatch for request %s: expected %s; %s, got %s; %s", httpResp.Request.URL,
-			MIMETypeJSON, ChartSetUTF8, contentType.MIMEType, contentType.ChartSet)
+			mimeType, ChartSetUTF8, contentType.MIMEType, contentType.ChartSet)
+	}
+
+	var body []byte
+	if c.strictDecoding && mimeType == MIMETypeJSON {
+		if body, err = io.ReadAll(httpResp.Body); err != nil {
+			return fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		httpResp.Body = io.NopCloser(bytes.NewReader(body))
 	}
 
 	if err = resp.UnmarshalResponse(httpResp); err != nil {
 		return fmt.Errorf("failed to convert HTTP response to API response: %w", err)
 	}
+	c.processResponseSchema(ctx, resp, e, schema)
+	if body != nil {
+		c.processUnknownFields(ctx, resp, e, body)
+	}
 
 	if resp.Failed() {
 		return fmt.Errorf("HTTP request failed: %w", resp.Err())
@@ -127,33 +281,32 @@ func NewClient(opts *Config) (*Client, error) {
 
 	client := &Client{}
 
-	// NOTE: user task caching does not work properly on Cruise Control end as it returns stalled responses.
-	// jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// client.httpClient = &http.Client{
-	// 	Transport: http.DefaultTransport,
-	// 	Jar:       jar,
-	// 	Timeout:   0,
-	// }
+	// NOTE: a client wide cookie jar is not used as Cruise Control returns stale responses for unrelated requests
+	// sharing the same session. Session cookies are kept per logical operation instead, see ContextWithSession.
 
 	client.httpClient = opts.HTTPClient
 	if client.httpClient == nil {
+		var transport http.RoundTripper = http.DefaultTransport
+		if opts.TLSConfig != nil {
+			t := http.DefaultTransport.(*http.Transport).Clone()
+			t.TLSClientConfig = opts.TLSConfig
+			transport = t
+		}
 		client.httpClient = &http.Client{
-			Transport: http.DefaultTransport,
+			Transport: transport,
 		}
 	}
 
-	serverURL := opts.ServerURL
-	if serverURL == "" {
-		serverURL = DefaultServerURL
-	}
-	if !strings.HasSuffix(serverURL, "/") {
-		serverURL += "/"
+	serverURLs := opts.ServerURLs
+	if len(serverURLs) == 0 {
+		serverURL := opts.ServerURL
+		if serverURL == "" {
+			serverURL = DefaultServerURL
+		}
+		serverURLs = []string{serverURL}
 	}
-	if client.url, err = url.Parse(serverURL); err != nil {
-		return nil, fmt.Errorf("failed to parse Cruise Control server URL: %w", err)
+	if client.servers, err = newServerPool(serverURLs, opts.FailoverRetryInterval); err != nil {
+		return nil, err
 	}
 
 	switch opts.AuthType {
@@ -177,6 +330,21 @@ func NewClient(opts *Config) (*Client, error) {
 		client.userAgent = DefaultUserAgent
 	}
 
+	client.unsupportedParams = opts.UnsupportedParams
+	client.compatibility = DefaultCompatibilityTable()
+	if opts.Compatibility != nil {
+		client.compatibility = *opts.Compatibility
+	}
+	client.version = &serverVersionCache{}
+
+	client.strictDecoding = opts.StrictDecoding
+	client.responseSchemaCheck = opts.ResponseSchemaCheck
+	client.schemas = &schemaCache{}
+	client.limiters = newEndpointLimiters(opts.EndpointLimits, opts.OnLimitWait)
+	client.cache = newResponseCache(opts.ResponseCacheTTLs)
+	client.cluster = opts.ClusterName
+	client.auditLog = opts.AuditLog
+
 	return client, nil
 }
 
@@ -0,0 +1,102 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const testServerPath = "/kafkacruisecontrol"
+
+// fakeCruiseControl is a Cruise Control server which serves the endpoints using the registered handlers and
+// counts the requests it receives per endpoint. Requests to endpoints without a handler get 404.
+type fakeCruiseControl struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[types.APIEndpoint]http.HandlerFunc
+	requests map[types.APIEndpoint]int
+}
+
+func newFakeCruiseControl(t *testing.T) *fakeCruiseControl {
+	t.Helper()
+
+	f := &fakeCruiseControl{
+		handlers: make(map[types.APIEndpoint]http.HandlerFunc),
+		requests: make(map[types.APIEndpoint]int),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := types.APIEndpoint(strings.ToUpper(path.Base(r.URL.Path)))
+
+		f.mu.Lock()
+		f.requests[e]++
+		h, ok := f.handlers[e]
+		f.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeCruiseControl) URL() string {
+	return f.Server.URL + testServerPath
+}
+
+func (f *fakeCruiseControl) handle(e types.APIEndpoint, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[e] = h
+}
+
+func (f *fakeCruiseControl) count(e types.APIEndpoint) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[e]
+}
+
+// writeJSON writes a JSON response with the status code and body. The User-Task-ID header is set if taskID is
+// not empty.
+func writeJSON(w http.ResponseWriter, status int, taskID, body string) {
+	w.Header().Set(HTTPHeaderContentType, MIMETypeJSON)
+	if taskID != "" {
+		w.Header().Set(types.UserTaskIDHTTPHeader, taskID)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func newTestClient(t *testing.T, config *Config) *Client {
+	t.Helper()
+
+	c, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
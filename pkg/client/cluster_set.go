@@ -0,0 +1,211 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultClusterSetConcurrency = 8
+
+// ClusterSet holds API clients of multiple Cruise Control instances identified by the name of their Kafka cluster.
+type ClusterSet struct {
+	mu          sync.RWMutex
+	clients     map[string]*Client
+	concurrency int
+}
+
+// NewClusterSet returns a new ClusterSet with clients created from the provided per-cluster configuration.
+// At most concurrency clusters are queried in parallel, DefaultClusterSetConcurrency is used if it is not positive.
+func NewClusterSet(configs map[string]*Config, concurrency int) (*ClusterSet, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultClusterSetConcurrency
+	}
+	s := &ClusterSet{
+		clients:     make(map[string]*Client, len(configs)),
+		concurrency: concurrency,
+	}
+	for name, config := range configs {
+		if err := s.Add(name, config); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add creates a client for the cluster using the provided configuration. It replaces the client of the cluster
+// if it is already in the set. The name of the cluster is used in the audit log unless the configuration sets one.
+func (s *ClusterSet) Add(name string, config *Config) error {
+	if config.ClusterName == "" {
+		c := *config
+		c.ClusterName = name
+		config = &c
+	}
+	c, err := NewClient(config)
+	if err != nil {
+		return fmt.Errorf("failed to create client for cluster %s: %w", name, err)
+	}
+	s.AddClient(name, c)
+	return nil
+}
+
+// AddClient adds the client of the cluster to the set replacing the existing one if there is any.
+func (s *ClusterSet) AddClient(name string, c *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[name] = c
+}
+
+// Remove removes the client of the cluster from the set.
+func (s *ClusterSet) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, name)
+}
+
+// Get returns the client of the cluster.
+func (s *ClusterSet) Get(name string) (*Client, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.clients[name]
+	return c, ok
+}
+
+// Names returns the names of the clusters in the set in alphabetical order.
+func (s *ClusterSet) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.clients))
+	for name := range s.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClusterResult is the result of a call made to the Cruise Control of a single cluster.
+type ClusterResult[T any] struct {
+	Cluster  string
+	Value    T
+	Err      error
+	Duration time.Duration
+}
+
+// ClusterResults are the per-cluster results of a call made using a ClusterSet in the order of the cluster names.
+type ClusterResults[T any] []ClusterResult[T]
+
+// Values returns the values of the successful calls by cluster name.
+func (r ClusterResults[T]) Values() map[string]T {
+	values := make(map[string]T, len(r))
+	for _, res := range r {
+		if res.Err == nil {
+			values[res.Cluster] = res.Value
+		}
+	}
+	return values
+}
+
+// Errors returns the errors of the failed calls by cluster name.
+func (r ClusterResults[T]) Errors() map[string]error {
+	errs := make(map[string]error)
+	for _, res := range r {
+		if res.Err != nil {
+			errs[res.Cluster] = res.Err
+		}
+	}
+	return errs
+}
+
+// Err returns the errors of the failed calls joined together or nil if every call succeeded.
+func (r ClusterResults[T]) Err() error {
+	errs := make([]error, 0)
+	for _, res := range r {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", res.Cluster, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Filter returns the results of the successful calls for which f returns true.
+func (r ClusterResults[T]) Filter(f func(T) bool) ClusterResults[T] {
+	filtered := make(ClusterResults[T], 0)
+	for _, res := range r {
+		if res.Err == nil && f(res.Value) {
+			filtered = append(filtered, res)
+		}
+	}
+	return filtered
+}
+
+// FanOut calls f with the client of each selected cluster with bounded concurrency and returns the per-cluster
+// results. Every cluster is selected if clusters is empty. Clusters which are not in the set and clusters
+// which are not called due to the context being cancelled are reported with an error.
+func FanOut[T any](ctx context.Context, s *ClusterSet, clusters []string,
+	f func(ctx context.Context, cluster string, c *Client) (T, error),
+) ClusterResults[T] {
+	if len(clusters) == 0 {
+		clusters = s.Names()
+	}
+
+	results := make(ClusterResults[T], len(clusters))
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, name := range clusters {
+		results[i].Cluster = name
+
+		c, ok := s.Get(name)
+		if !ok {
+			results[i].Err = fmt.Errorf("cluster %s is not in the cluster set", name)
+			continue
+		}
+
+		// Both cases of the select might be ready, so the context is checked first to not start calls after
+		// it got cancelled.
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *ClusterResult[T], c *Client) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			res.Value, res.Err = f(ctx, res.Cluster, c)
+			res.Duration = time.Since(start)
+		}(&results[i], c)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Cluster < results[j].Cluster })
+	return results
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func newTestClusterSet(t *testing.T, concurrency int, names ...string) *ClusterSet {
+	t.Helper()
+
+	configs := make(map[string]*Config, len(names))
+	for _, name := range names {
+		configs[name] = &Config{ServerURL: "http://" + name + ":8090/kafkacruisecontrol"}
+	}
+	s, err := NewClusterSet(configs, concurrency)
+	if err != nil {
+		t.Fatalf("failed to create cluster set: %v", err)
+	}
+	return s
+}
+
+func TestClusterSet(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := newTestClusterSet(t, 0, "b", "a")
+	g.Expect(s.concurrency).To(Equal(DefaultClusterSetConcurrency))
+	g.Expect(s.Names()).To(Equal([]string{"a", "b"}))
+
+	c, ok := s.Get("a")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(c.cluster).To(Equal("a"))
+
+	// The cluster name of the configuration takes precedence.
+	g.Expect(s.Add("c", &Config{ServerURL: "http://c:8090/kafkacruisecontrol", ClusterName: "kafka-c"})).To(Succeed())
+	c, ok = s.Get("c")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(c.cluster).To(Equal("kafka-c"))
+
+	s.Remove("c")
+	_, ok = s.Get("c")
+	g.Expect(ok).To(BeFalse())
+	g.Expect(s.Names()).To(Equal([]string{"a", "b"}))
+}
+
+func TestFanOut(t *testing.T) {
+	ctx := context.Background()
+	clusterName := func(_ context.Context, cluster string, _ *Client) (string, error) {
+		return cluster, nil
+	}
+
+	t.Run("Results are ordered by cluster name", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := newTestClusterSet(t, 2, "a", "b", "c", "d")
+		results := FanOut(ctx, s, []string{"c", "a", "d"}, clusterName)
+		g.Expect(results.Err()).NotTo(HaveOccurred())
+
+		clusters := make([]string, 0)
+		for _, res := range results {
+			g.Expect(res.Value).To(Equal(res.Cluster))
+			clusters = append(clusters, res.Cluster)
+		}
+		g.Expect(clusters).To(Equal([]string{"a", "c", "d"}))
+
+		// Every cluster is called if none is selected.
+		g.Expect(FanOut(ctx, s, nil, clusterName)).To(HaveLen(4))
+	})
+
+	t.Run("Concurrency is limited", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var active, peak int32
+		s := newTestClusterSet(t, 2, "a", "b", "c", "d", "e")
+		results := FanOut(ctx, s, nil, func(_ context.Context, _ string, _ *Client) (int32, error) {
+			n := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return n, nil
+		})
+		g.Expect(results.Err()).NotTo(HaveOccurred())
+		g.Expect(peak).To(Equal(int32(2)))
+		for _, res := range results {
+			g.Expect(res.Duration).To(BeNumerically(">=", 20*time.Millisecond))
+		}
+	})
+
+	t.Run("Unknown clusters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var mu sync.Mutex
+		called := make([]string, 0)
+		s := newTestClusterSet(t, 2, "a")
+		results := FanOut(ctx, s, []string{"missing", "a"}, func(_ context.Context, cluster string, _ *Client) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			called = append(called, cluster)
+			return cluster, nil
+		})
+		g.Expect(called).To(Equal([]string{"a"}))
+		g.Expect(results.Values()).To(Equal(map[string]string{"a": "a"}))
+		g.Expect(results.Errors()).To(HaveKey("missing"))
+		g.Expect(results.Err()).To(MatchError(ContainSubstring("cluster missing")))
+	})
+
+	t.Run("Cancellation stops starting calls", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var calls int32
+		s := newTestClusterSet(t, 1, "a", "b", "c")
+		results := FanOut(ctx, s, nil, func(_ context.Context, cluster string, _ *Client) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			// The call keeps the only slot until the cancellation is observed by the fan-out.
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			return cluster, nil
+		})
+		g.Expect(calls).To(Equal(int32(1)))
+		g.Expect(results.Values()).To(Equal(map[string]string{"a": "a"}))
+		g.Expect(errors.Is(results.Errors()["b"], context.Canceled)).To(BeTrue())
+		g.Expect(errors.Is(results.Errors()["c"], context.Canceled)).To(BeTrue())
+		g.Expect(errors.Is(results.Err(), context.Canceled)).To(BeTrue())
+	})
+}
+
+func TestClusterResults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	boom := errors.New("boom")
+	results := ClusterResults[int]{
+		{Cluster: "a", Value: 1},
+		{Cluster: "b", Err: boom},
+		{Cluster: "c", Value: 3},
+	}
+
+	g.Expect(results.Values()).To(Equal(map[string]int{"a": 1, "c": 3}))
+	g.Expect(results.Errors()).To(Equal(map[string]error{"b": boom}))
+	g.Expect(results.Err()).To(MatchError(ContainSubstring("cluster b: boom")))
+	g.Expect(errors.Is(results.Err(), boom)).To(BeTrue())
+
+	filtered := results.Filter(func(v int) bool { return v > 1 })
+	g.Expect(filtered).To(Equal(ClusterResults[int]{{Cluster: "c", Value: 3}}))
+
+	g.Expect(results[:1].Err()).NotTo(HaveOccurred())
+	g.Expect(results[:1].Errors()).To(BeEmpty())
+}
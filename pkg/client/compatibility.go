@@ -0,0 +1,228 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	// UnsupportedParamsPolicyIgnore sends the requests as they are regardless of the server version.
+	UnsupportedParamsPolicyIgnore UnsupportedParamsPolicy = iota
+	// UnsupportedParamsPolicyReject fails requests which use parameters or endpoints unsupported by the server.
+	UnsupportedParamsPolicyReject
+	// UnsupportedParamsPolicyDrop removes unsupported parameters from requests and logs a warning.
+	// Requests sent to unsupported endpoints are rejected.
+	UnsupportedParamsPolicyDrop
+)
+
+// UnsupportedParamsPolicy defines how requests with parameters unsupported by the server are handled.
+type UnsupportedParamsPolicy int8
+
+func (p UnsupportedParamsPolicy) String() string {
+	switch p {
+	case UnsupportedParamsPolicyReject:
+		return "REJECT"
+	case UnsupportedParamsPolicyDrop:
+		return "DROP"
+	case UnsupportedParamsPolicyIgnore:
+		fallthrough
+	default:
+		return "IGNORE"
+	}
+}
+
+func UnsupportedParamsPolicyFromString(s string) UnsupportedParamsPolicy {
+	switch s {
+	case UnsupportedParamsPolicyReject.String():
+		return UnsupportedParamsPolicyReject
+	case UnsupportedParamsPolicyDrop.String():
+		return UnsupportedParamsPolicyDrop
+	default:
+		return UnsupportedParamsPolicyIgnore
+	}
+}
+
+// CompatibilityTable holds the minimum Cruise Control versions required by endpoints and request parameters.
+// Endpoints and parameters missing from the table are considered supported by every version.
+type CompatibilityTable struct {
+	Endpoints map[types.APIEndpoint]types.ServerVersion
+	Params    map[types.APIEndpoint]map[string]types.ServerVersion
+}
+
+// DefaultCompatibilityTable returns the compatibility table based on the Cruise Control release notes.
+//
+// The versions are the releases which introduced the endpoints and parameters, not the oldest release supported by
+// the client, so some of them predate 2.5.94. The client is still used with such servers in practice, and for them
+// the table turns silently ignored parameters into UnsupportedError or dropped parameters.
+func DefaultCompatibilityTable() CompatibilityTable {
+	v2511 := types.MustParseServerVersion("2.5.11")
+	v2543 := types.MustParseServerVersion("2.5.43")
+	v2567 := types.MustParseServerVersion("2.5.67")
+	v2597 := types.MustParseServerVersion("2.5.97")
+
+	return CompatibilityTable{
+		Endpoints: map[types.APIEndpoint]types.ServerVersion{
+			api.EndpointRightsize: v2567,
+		},
+		Params: map[types.APIEndpoint]map[string]types.ServerVersion{
+			api.EndpointAddBroker: {
+				"throttle_added_broker": v2543,
+			},
+			api.EndpointProposals: {
+				"rebalance_disk": v2511,
+			},
+			api.EndpointRebalance: {
+				"rebalance_disk": v2511,
+			},
+			api.EndpointRemoveBroker: {
+				"throttle_removed_broker":            v2543,
+				"max_partition_movements_in_cluster": v2597,
+			},
+		},
+	}
+}
+
+// UnsupportedError is returned if a request uses an endpoint or a parameter unsupported by the server.
+type UnsupportedError struct {
+	Endpoint types.APIEndpoint
+	// Param is empty if the endpoint itself is unsupported.
+	Param    string
+	Required types.ServerVersion
+	Server   types.ServerVersion
+}
+
+func (e UnsupportedError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("endpoint %s requires Cruise Control %s or newer (server version: %s)",
+			e.Endpoint, e.Required, e.Server)
+	}
+	return fmt.Sprintf("parameter %s of endpoint %s requires Cruise Control %s or newer (server version: %s)",
+		e.Param, e.Endpoint, e.Required, e.Server)
+}
+
+type serverVersionCache struct {
+	mu      sync.RWMutex
+	version types.ServerVersion
+}
+
+func (c *serverVersionCache) get() (types.ServerVersion, bool) {
+	if c == nil {
+		return types.ServerVersion{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version, !c.version.IsZero()
+}
+
+func (c *serverVersionCache) update(s string) {
+	if c == nil || s == "" {
+		return
+	}
+	v, err := types.ParseServerVersion(s)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version = v
+}
+
+// ServerVersion returns the version of the Cruise Control server. The version is cached and updated from
+// the responses of every request sent by the client, so the server is only queried if no request was sent before.
+func (c *Client) ServerVersion(ctx context.Context) (types.ServerVersion, error) {
+	if v, ok := c.version.get(); ok {
+		return v, nil
+	}
+
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateExecutor}
+	resp, err := c.State(ctx, req)
+	if err != nil {
+		return types.ServerVersion{}, fmt.Errorf("failed to discover Cruise Control version: %w", err)
+	}
+
+	v, err := types.ParseServerVersion(resp.CruiseControlVersion)
+	if err != nil {
+		return types.ServerVersion{}, fmt.Errorf("failed to discover Cruise Control version: %w", err)
+	}
+	return v, nil
+}
+
+// checkCompatibility applies the UnsupportedParamsPolicy to the request sent to the endpoint.
+func (c Client) checkCompatibility(ctx context.Context, r *http.Request, e types.APIEndpoint) error {
+	if c.unsupportedParams == UnsupportedParamsPolicyIgnore {
+		return nil
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	if r.URL == nil {
+		r.URL = &url.URL{}
+	}
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query parameters of request: %w", err)
+	}
+
+	endpointVersion, gatedEndpoint := c.compatibility.Endpoints[e]
+	gatedParams := make([]string, 0)
+	for p := range c.compatibility.Params[e] {
+		if query.Has(p) {
+			gatedParams = append(gatedParams, p)
+		}
+	}
+	if !gatedEndpoint && len(gatedParams) == 0 {
+		return nil
+	}
+	sort.Strings(gatedParams)
+
+	server, err := c.ServerVersion(ctx)
+	if err != nil {
+		log.Info("skipping compatibility check as Cruise Control version is unknown", "endpoint", e, "error", err.Error())
+		return nil
+	}
+
+	if gatedEndpoint && !server.AtLeast(endpointVersion) {
+		return UnsupportedError{Endpoint: e, Required: endpointVersion, Server: server}
+	}
+
+	for _, p := range gatedParams {
+		required := c.compatibility.Params[e][p]
+		if server.AtLeast(required) {
+			continue
+		}
+		if c.unsupportedParams == UnsupportedParamsPolicyReject {
+			return UnsupportedError{Endpoint: e, Param: p, Required: required, Server: server}
+		}
+		log.Info("WARNING: dropping parameter unsupported by Cruise Control", "endpoint", e, "param", p,
+			"required", required.String(), "server", server.String())
+		query.Del(p)
+	}
+	r.URL.RawQuery = query.Encode()
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// newVersionedCruiseControl returns a fake server reporting the version in every response which records the query
+// of the latest REMOVE_BROKER request.
+func newVersionedCruiseControl(t *testing.T, version string, query *string) *fakeCruiseControl {
+	t.Helper()
+
+	server := newFakeCruiseControl(t)
+	server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(types.CruiseControlVersionHTTPHeader, version)
+		writeJSON(w, http.StatusOK, "", `{}`)
+	})
+	server.handle(api.EndpointRemoveBroker, func(w http.ResponseWriter, r *http.Request) {
+		*query = r.URL.RawQuery
+		w.Header().Set(types.CruiseControlVersionHTTPHeader, version)
+		writeJSON(w, http.StatusOK, "", `{}`)
+	})
+	return server
+}
+
+func removeBrokerRequest() *api.RemoveBrokerRequest {
+	req := api.RemoveBrokerRequestWithDefaults()
+	req.BrokerIDs = []int32{1}
+	req.ThrottleRemovedBroker = true
+	req.MaxPartitionMovementsInCluster = 100
+	return req
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	t.Run("Drops unsupported parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var query string
+		server := newVersionedCruiseControl(t, "2.5.94", &query)
+		c := newTestClient(t, &Config{ServerURL: server.URL(), UnsupportedParams: UnsupportedParamsPolicyDrop})
+
+		_, err := c.RemoveBroker(context.Background(), removeBrokerRequest())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(query).To(ContainSubstring("throttle_removed_broker=true"))
+		g.Expect(query).NotTo(ContainSubstring("max_partition_movements_in_cluster"))
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+
+		// The version is cached from the response headers.
+		_, err = c.RemoveBroker(context.Background(), removeBrokerRequest())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+
+	t.Run("Rejects unsupported parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var query string
+		server := newVersionedCruiseControl(t, "2.5.94", &query)
+		c := newTestClient(t, &Config{ServerURL: server.URL(), UnsupportedParams: UnsupportedParamsPolicyReject})
+
+		_, err := c.RemoveBroker(context.Background(), removeBrokerRequest())
+		var unsupported UnsupportedError
+		g.Expect(errors.As(err, &unsupported)).To(BeTrue())
+		g.Expect(unsupported.Endpoint).To(Equal(api.EndpointRemoveBroker))
+		g.Expect(unsupported.Param).To(Equal("max_partition_movements_in_cluster"))
+		g.Expect(unsupported.Required).To(Equal(types.MustParseServerVersion("2.5.97")))
+		g.Expect(unsupported.Server).To(Equal(types.MustParseServerVersion("2.5.94")))
+		g.Expect(server.count(api.EndpointRemoveBroker)).To(BeZero())
+	})
+
+	t.Run("Rejects unsupported endpoint", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := newTestClient(t, &Config{UnsupportedParams: UnsupportedParamsPolicyDrop})
+		c.version.update("2.5.50")
+
+		_, err := c.Rightsize(context.Background(), api.RightsizeRequestWithDefaults())
+		var unsupported UnsupportedError
+		g.Expect(errors.As(err, &unsupported)).To(BeTrue())
+		g.Expect(unsupported.Endpoint).To(Equal(api.EndpointRightsize))
+		g.Expect(unsupported.Param).To(BeEmpty())
+	})
+
+	t.Run("Sends supported parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var query string
+		server := newVersionedCruiseControl(t, "2.5.101", &query)
+		c := newTestClient(t, &Config{ServerURL: server.URL(), UnsupportedParams: UnsupportedParamsPolicyReject})
+
+		_, err := c.RemoveBroker(context.Background(), removeBrokerRequest())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(query).To(ContainSubstring("max_partition_movements_in_cluster=100"))
+	})
+
+	t.Run("Sends request as is if version is unknown", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var query string
+		server := newVersionedCruiseControl(t, "", &query)
+		c := newTestClient(t, &Config{ServerURL: server.URL(), UnsupportedParams: UnsupportedParamsPolicyReject})
+
+		_, err := c.RemoveBroker(context.Background(), removeBrokerRequest())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(query).To(ContainSubstring("max_partition_movements_in_cluster=100"))
+	})
+}
+
+func TestDefaultCompatibilityTable(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	table := DefaultCompatibilityTable()
+	g.Expect(table.Params[api.EndpointProposals]).To(HaveKey("rebalance_disk"))
+	g.Expect(table.Params[api.EndpointProposals]["rebalance_disk"]).To(Equal(table.Params[api.EndpointRebalance]["rebalance_disk"]))
+}
@@ -17,37 +17,100 @@ limitations under the License.
 package client
 
 import (
+	"crypto/tls"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
 )
 
 const (
 	prefix            = "CC_"
 	ServerURLEnvKey   = prefix + "SERVER_URL"
+	ServerURLsEnvKey  = prefix + "SERVER_URLS"
 	AuthTypeEnvKey    = prefix + "AUTH_TYPE"
 	UsernameEnvKey    = prefix + "USERNAME"
 	PasswordEnvKey    = prefix + "PASSWORD"
 	AccessTokenEnvKey = prefix + "ACCESS_TOKEN"
 	UserAgentEnvKey   = prefix + "USER_AGENT"
+
+	UnsupportedParamsEnvKey = prefix + "UNSUPPORTED_PARAMS"
 )
 
 // Config contains the configuration parameters for the API Client
 type Config struct {
+	// ClusterName identifies the Kafka cluster managed by Cruise Control in the audit log.
+	ClusterName string
 	ServerURL   string
+	// ServerURLs is the ordered list of the URLs of Cruise Control instances serving the same Kafka cluster.
+	// Requests are sent to the first healthy instance. GET requests fail over to the next one on connection errors
+	// or server errors, while other requests only fail over if the connection could not be established.
+	// ServerURL is ignored if set.
+	ServerURLs []string
+	// FailoverRetryInterval is the time an instance is skipped for after a failed request.
+	// DefaultFailoverRetryInterval is used if not set.
+	FailoverRetryInterval time.Duration
+
 	AuthType    AuthType
 	Username    string
 	Password    string
 	AccessToken string
 	UserAgent   string
 
+	// UnsupportedParams defines how requests using endpoints or parameters which are not supported by the version
+	// of the Cruise Control server are handled. By default, requests are sent as they are.
+	UnsupportedParams UnsupportedParamsPolicy
+	// Compatibility overrides the default compatibility table used for checking requests against the server version.
+	Compatibility *CompatibilityTable
+	// StrictDecoding enables collecting the fields of the response bodies which are unknown to the Go types they are
+	// decoded into. Unknown fields are reported in the Diagnostics field of the responses and logged using the logger
+	// of the request context instead of failing the request.
+	StrictDecoding bool
+	// ResponseSchemaCheck enables requesting the JSON schema of every response from Cruise Control and reporting
+	// both the fields unknown to the Go types and the fields of the Go types missing from the schema in the
+	// Diagnostics field of the responses. Schemas returned by Cruise Control are cached and available using
+	// Client.ResponseSchema regardless of this option.
+	ResponseSchemaCheck bool
+
+	// EndpointLimits defines the rate and concurrency limits of requests sent to the endpoints.
+	// Requests waiting for the limits honour the deadline of their context.
+	EndpointLimits map[types.APIEndpoint]EndpointLimit
+	// OnLimitWait is called with the time every request to an endpoint with limits spent waiting for them.
+	OnLimitWait LimitWaitObserver
+
+	// ResponseCacheTTLs enables caching the responses of the read-only endpoints for the provided time.
+	// Concurrent identical requests are coalesced and the cache is invalidated by every mutating request
+	// sent by the client. Requests sent in a session or pinned to a server bypass the cache.
+	// See DefaultResponseCacheTTLs.
+	ResponseCacheTTLs map[types.APIEndpoint]time.Duration
+
+	// AuditLog records every mutating request sent by the client. Failing to record a request is logged using
+	// the logger of the request context and does not fail the request.
+	AuditLog audit.Store
+
+	// TLSConfig is used for connecting to Cruise Control over HTTPS. It is ignored if HTTPClient is set.
+	TLSConfig *tls.Config
+
 	HTTPClient *http.Client
 }
 
 func (c *Config) ReadFromEnvironment() {
 	c.ServerURL = os.Getenv(ServerURLEnvKey)
+	if urls := os.Getenv(ServerURLsEnvKey); urls != "" {
+		c.ServerURLs = nil
+		for _, u := range strings.Split(urls, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				c.ServerURLs = append(c.ServerURLs, u)
+			}
+		}
+	}
 	c.AuthType = AuthTypeFromString(os.Getenv(AuthTypeEnvKey))
 	c.Username = os.Getenv(UsernameEnvKey)
 	c.Password = os.Getenv(PasswordEnvKey)
 	c.AccessToken = os.Getenv(AccessTokenEnvKey)
 	c.UserAgent = os.Getenv(UserAgentEnvKey)
+	c.UnsupportedParams = UnsupportedParamsPolicyFromString(os.Getenv(UnsupportedParamsEnvKey))
 }
@@ -44,3 +44,17 @@ func parseContentType(s string) ContentType {
 	}
 	return contentType
 }
+
+// Matches returns true if the content type is acceptable for a response expected to be in the given MIME type.
+// UTF-8 encoded responses are accepted where JSON is expected, while JSON responses are accepted where plain text
+// is expected as some errors are reported in JSON format regardless of the requested format.
+func (c ContentType) Matches(mimeType string) bool {
+	switch mimeType {
+	case MIMETypeJSON:
+		return c.MIMEType == MIMETypeJSON || c.ChartSet == ChartSetUTF8
+	case MIMETypeTextPlain:
+		return c.MIMEType == MIMETypeTextPlain || c.MIMEType == MIMETypeJSON
+	default:
+		return c.MIMEType == mimeType
+	}
+}
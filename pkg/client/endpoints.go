@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
 )
 
 func (c *Client) AddBroker(ctx context.Context, r *api.AddBrokerRequest) (*api.AddBrokerResponse, error) {
@@ -134,3 +135,30 @@ func (c *Client) UserTasks(ctx context.Context, r *api.UserTasksRequest) (*api.U
 	resp := &api.UserTasksResponse{}
 	return resp, c.request(ctx, r, resp, api.EndpointUserTasks, http.MethodGet)
 }
+
+// Text returns the human-readable (plain text) response of the endpoint for the request.
+func (c *Client) Text(ctx context.Context, e types.APIEndpoint, r interface{}) (*api.TextResponse, error) {
+	resp := &api.TextResponse{}
+	return resp, c.do(ctx, r, resp, e, endpointMethod(e), MIMETypeTextPlain,
+		WithAcceptText(),
+		WithTextQuery(),
+	)
+}
+
+// endpointMethod returns the HTTP method used for sending requests to the endpoint.
+func endpointMethod(e types.APIEndpoint) string {
+	switch e {
+	case api.EndpointBootstrap,
+		api.EndpointKafkaClusterLoad,
+		api.EndpointKafkaClusterState,
+		api.EndpointKafkaPartitionLoad,
+		api.EndpointProposals,
+		api.EndpointReviewBoard,
+		api.EndpointState,
+		api.EndpointTrain,
+		api.EndpointUserTasks:
+		return http.MethodGet
+	default:
+		return http.MethodPost
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultExecutionPollInterval = 5 * time.Second
+	DefaultExecutionStopTimeout  = 5 * time.Minute
+)
+
+// ExecutionOptions contains the parameters for running a mutating request with Execute.
+type ExecutionOptions struct {
+	// Interval between checking the state of the execution. DefaultExecutionPollInterval is used if not set.
+	PollInterval time.Duration
+	// Maximum time to wait for the execution to stop after the context got cancelled.
+	// DefaultExecutionStopTimeout is used if not set.
+	StopTimeout time.Duration
+	// Passed to the StopProposalExecution request issued when the context is cancelled.
+	ForceStop         bool
+	StopExternalAgent bool
+}
+
+// ExecutionReport describes the outcome of a request started with Execute.
+type ExecutionReport struct {
+	// ID of the user task which triggered the execution.
+	UserTaskID string
+	// Final status of the user task. It is undefined if the status could not be determined.
+	Status types.UserTaskStatus
+	// Aborted is true if the execution was stopped due to the cancellation of the context.
+	Aborted bool
+	// Executor state observed right before the execution was stopped. It holds the pending and in progress
+	// movements which got aborted. It is nil if the execution was not stopped.
+	AbortedState *types.ExecutorState
+	// Executor state observed after the execution was stopped.
+	FinalState *types.ExecutorState
+}
+
+// AbortedMovements returns the partition movements which were pending or in progress when the execution
+// was stopped.
+func (r ExecutionReport) AbortedMovements() []types.ExecutionTask {
+	if r.AbortedState == nil {
+		return nil
+	}
+	s := r.AbortedState
+	tasks := make([]types.ExecutionTask, 0,
+		len(s.PendingPartitionMovement)+len(s.InProgressPartitionMovement)+
+			len(s.PendingIntraBrokerPartitionMovement)+len(s.InProgressIntraBrokerPartitionMovement))
+	tasks = append(tasks, s.PendingPartitionMovement...)
+	tasks = append(tasks, s.InProgressPartitionMovement...)
+	tasks = append(tasks, s.PendingIntraBrokerPartitionMovement...)
+	tasks = append(tasks, s.InProgressIntraBrokerPartitionMovement...)
+	return tasks
+}
+
+// ExecutionClient is the subset of the Client API which Execute uses to watch and stop executions.
+type ExecutionClient interface {
+	State(ctx context.Context, r *api.StateRequest) (*api.StateResponse, error)
+	UserTasks(ctx context.Context, r *api.UserTasksRequest) (*api.UserTasksResponse, error)
+	StopProposalExecution(ctx context.Context, r *api.StopProposalExecutionRequest) (*api.StopProposalExecutionResponse, error) //nolint:lll
+}
+
+type userTaskIdentifier interface {
+	UserTaskID() string
+}
+
+// Execute sends a mutating request using call and waits for the triggered execution to finish. If the context is
+// cancelled or times out before the execution finishes, the execution is stopped using the StopProposalExecution
+// API and Execute waits until the executor returns to NO_TASK_IN_PROGRESS state. Only executions triggered by the
+// user task of the request are stopped.
+//
+// Example:
+//
+//	resp, report, err := client.Execute(ctx, cc, cc.Rebalance, req, nil)
+func Execute[Req any, Resp types.APIResponse](ctx context.Context, c ExecutionClient, call func(context.Context, Req) (Resp, error),
+	r Req, opts *ExecutionOptions,
+) (Resp, *ExecutionReport, error) {
+	if opts == nil {
+		opts = &ExecutionOptions{}
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultExecutionPollInterval
+	}
+	if opts.StopTimeout <= 0 {
+		opts.StopTimeout = DefaultExecutionStopTimeout
+	}
+
+	resp, err := call(ctx, r)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	report := &ExecutionReport{}
+	if t, ok := any(resp).(userTaskIdentifier); ok {
+		report.UserTaskID = t.UserTaskID()
+	}
+	if report.UserTaskID == "" {
+		return resp, report, errors.New("response does not contain user task ID")
+	}
+
+	// The user task and the execution it triggered are only known by the Cruise Control server which received
+	// the request, so the following requests are pinned to it.
+	return resp, report, watchExecution(ContextWithUserTaskID(ctx, report.UserTaskID), c, report, opts)
+}
+
+func watchExecution(ctx context.Context, c ExecutionClient, report *ExecutionReport, opts *ExecutionOptions) error {
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := userTaskDone(ctx, c, report)
+		switch {
+		case done:
+			return nil
+		case err != nil && ctx.Err() == nil:
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return abortExecution(ctx, c, report, opts)
+		case <-ticker.C:
+		}
+	}
+}
+
+func userTaskDone(ctx context.Context, c ExecutionClient, report *ExecutionReport) (bool, error) {
+	req := api.UserTasksRequestWithDefaults()
+	req.UserTaskIDs = []string{report.UserTaskID}
+
+	resp, err := c.UserTasks(ctx, req)
+	if err != nil {
+		return false, fmt.Errorf("failed to get status of user task %s: %w", report.UserTaskID, err)
+	}
+	if resp.Result == nil || len(resp.Result.UserTasks) == 0 {
+		return false, errors.Errorf("user task %s does not exist", report.UserTaskID)
+	}
+
+	report.Status = resp.Result.UserTasks[0].Status
+	return report.Status == types.UserTaskStatusCompleted || report.Status == types.UserTaskStatusCompletedWithError, nil
+}
+
+func executorState(ctx context.Context, c ExecutionClient) (*types.ExecutorState, error) {
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateExecutor}
+	req.Verbose = true
+
+	resp, err := c.State(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executor state: %w", err)
+	}
+	if resp.Result == nil {
+		return nil, errors.New("executor state is not available")
+	}
+	return &resp.Result.ExecutorState, nil
+}
+
+// abortExecution stops the execution triggered by the user task of the report. As the original context is already
+// cancelled, a new context bound by the stop timeout is used which keeps the values of the original one.
+func abortExecution(parent context.Context, c ExecutionClient, report *ExecutionReport, opts *ExecutionOptions) error {
+	cause := parent.Err()
+	log := logr.FromContextOrDiscard(parent)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), opts.StopTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// The user task might still be computing proposals, so wait until either it finishes or
+		// the executor picks it up.
+		done, err := userTaskDone(ctx, c, report)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Requests in flight when the stop timeout expires fail with the error of the context.
+			return fmt.Errorf("timed out waiting for execution of user task %s to start: %w", report.UserTaskID, cause)
+		case err != nil:
+			return fmt.Errorf("failed to stop execution of user task %s: %w", report.UserTaskID, err)
+		case done:
+			return fmt.Errorf("user task %s finished before its execution could be stopped: %w", report.UserTaskID, cause)
+		}
+
+		state, err := executorState(ctx, c)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return fmt.Errorf("timed out waiting for execution of user task %s to start: %w", report.UserTaskID, cause)
+		case err != nil:
+			return fmt.Errorf("failed to stop execution of user task %s: %w", report.UserTaskID, err)
+		}
+		if state.TriggeredUserTaskID == report.UserTaskID && state.State != types.ExecutorStateTypeNoTaskInProgress {
+			report.AbortedState = state
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for execution of user task %s to start: %w", report.UserTaskID, cause)
+		case <-ticker.C:
+		}
+	}
+
+	log.V(0).Info("stopping execution", "user_task_id", report.UserTaskID, "cause", cause)
+
+	req := api.StopProposalExecutionRequestWithDefaults()
+	req.ForceStop = opts.ForceStop
+	req.StopExternalAgent = opts.StopExternalAgent
+	if _, err := c.StopProposalExecution(ctx, req); err != nil {
+		return fmt.Errorf("failed to stop execution of user task %s: %w", report.UserTaskID, err)
+	}
+	report.Aborted = true
+
+	for {
+		state, err := executorState(ctx, c)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return fmt.Errorf("timed out waiting for execution of user task %s to stop: %w", report.UserTaskID, cause)
+		case err != nil:
+			return fmt.Errorf("failed to wait for execution of user task %s to stop: %w", report.UserTaskID, err)
+		}
+		if state.State == types.ExecutorStateTypeNoTaskInProgress {
+			report.FinalState = state
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for execution of user task %s to stop: %w", report.UserTaskID, cause)
+		case <-ticker.C:
+		}
+	}
+
+	return fmt.Errorf("execution of user task %s aborted: %w", report.UserTaskID, cause)
+}
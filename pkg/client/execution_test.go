@@ -0,0 +1,259 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const testUserTaskID = "5a3ca3c3-1b2d-4f64-9a4c-1a7b3f4c2d10"
+
+func userTasksHandler(status func() types.UserTaskStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, "", fmt.Sprintf(`{"userTasks":[{"UserTaskId":%q,"Status":%q}]}`,
+			testUserTaskID, status()))
+	}
+}
+
+func executorStateHandler(state func() (string, types.ExecutorStateType)) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		id, s := state()
+		writeJSON(w, http.StatusOK, "", fmt.Sprintf(`{"ExecutorState":{"triggeredUserTaskId":%q,"state":%q}}`, id, s))
+	}
+}
+
+func TestExecute(t *testing.T) {
+	opts := func() *ExecutionOptions {
+		return &ExecutionOptions{
+			PollInterval: 5 * time.Millisecond,
+			StopTimeout:  time.Second,
+		}
+	}
+
+	t.Run("Polls user task until it completes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		var polls atomic.Int32
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			if polls.Add(1) < 3 {
+				return types.UserTaskStatusInExecution
+			}
+			return types.UserTaskStatusCompletedWithError
+		}))
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		resp, report, err := Execute(context.Background(), c, c.Rebalance, api.RebalanceRequestWithDefaults(), opts())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resp.Result).NotTo(BeNil())
+		g.Expect(report.UserTaskID).To(Equal(testUserTaskID))
+		g.Expect(report.Status).To(Equal(types.UserTaskStatusCompletedWithError))
+		g.Expect(report.Aborted).To(BeFalse())
+		g.Expect(server.count(api.EndpointUserTasks)).To(Equal(3))
+		g.Expect(server.count(api.EndpointStopProposalExecution)).To(BeZero())
+	})
+
+	t.Run("Watches in progress (202) response", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusAccepted, testUserTaskID,
+				`{"version":1,"progress":[{"operation":"Rebalance","operationProgress":[{"step":"WAITING_FOR_CLUSTER_MODEL"}]}]}`)
+		})
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			return types.UserTaskStatusCompleted
+		}))
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		resp, report, err := Execute(context.Background(), c, c.Rebalance, api.RebalanceRequestWithDefaults(), opts())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resp.InProgress()).To(BeTrue())
+		g.Expect(resp.Result).To(BeNil())
+		g.Expect(report.UserTaskID).To(Equal(testUserTaskID))
+		g.Expect(report.Status).To(Equal(types.UserTaskStatusCompleted))
+	})
+
+	t.Run("Fails without user task ID", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusAccepted, "", `{"version":1,"progress":[]}`)
+		})
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		_, _, err := Execute(context.Background(), c, c.Rebalance, api.RebalanceRequestWithDefaults(), opts())
+		g.Expect(err).To(MatchError(ContainSubstring("response does not contain user task ID")))
+		g.Expect(server.count(api.EndpointUserTasks)).To(BeZero())
+	})
+
+	t.Run("Fails if user task does not exist", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		server.handle(api.EndpointUserTasks, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, "", `{"userTasks":[]}`)
+		})
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		_, _, err := Execute(context.Background(), c, c.Rebalance, api.RebalanceRequestWithDefaults(), opts())
+		g.Expect(err).To(MatchError(ContainSubstring("does not exist")))
+	})
+
+	t.Run("Stops execution when context is cancelled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var stopped atomic.Bool
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			return types.UserTaskStatusInExecution
+		}))
+		server.handle(api.EndpointState, executorStateHandler(func() (string, types.ExecutorStateType) {
+			if stopped.Load() {
+				return "", types.ExecutorStateTypeNoTaskInProgress
+			}
+			return testUserTaskID, types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress
+		}))
+		server.handle(api.EndpointStopProposalExecution, func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(r.Method).To(Equal(http.MethodPost))
+			g.Expect(r.URL.Query().Get("force_stop")).To(Equal("true"))
+			stopped.Store(true)
+			writeJSON(w, http.StatusOK, "", `{"message":"Proposal execution stopped."}`)
+		})
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		o := opts()
+		o.ForceStop = true
+		_, report, err := Execute(ctx, c, c.Rebalance, api.RebalanceRequestWithDefaults(), o)
+		g.Expect(err).To(MatchError(context.DeadlineExceeded))
+		g.Expect(err).To(MatchError(ContainSubstring("aborted")))
+		g.Expect(report.Aborted).To(BeTrue())
+		g.Expect(report.AbortedState).NotTo(BeNil())
+		g.Expect(report.AbortedState.State).To(Equal(types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress))
+		g.Expect(report.FinalState).NotTo(BeNil())
+		g.Expect(report.FinalState.State).To(Equal(types.ExecutorStateTypeNoTaskInProgress))
+		g.Expect(server.count(api.EndpointStopProposalExecution)).To(Equal(1))
+	})
+
+	t.Run("Does not stop execution of other user task", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			return types.UserTaskStatusActive
+		}))
+		server.handle(api.EndpointState, executorStateHandler(func() (string, types.ExecutorStateType) {
+			return "other-user-task", types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress
+		}))
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		o := opts()
+		o.StopTimeout = 50 * time.Millisecond
+		_, report, err := Execute(ctx, c, c.Rebalance, api.RebalanceRequestWithDefaults(), o)
+		g.Expect(err).To(MatchError(context.DeadlineExceeded))
+		g.Expect(err).To(MatchError(ContainSubstring("timed out waiting for execution of user task %s to start",
+			testUserTaskID)))
+		g.Expect(report.Aborted).To(BeFalse())
+		g.Expect(server.count(api.EndpointStopProposalExecution)).To(BeZero())
+	})
+
+	t.Run("Times out waiting for execution to stop", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			return types.UserTaskStatusInExecution
+		}))
+		server.handle(api.EndpointState, executorStateHandler(func() (string, types.ExecutorStateType) {
+			return testUserTaskID, types.ExecutorStateTypeStoppingExecution
+		}))
+		server.handle(api.EndpointStopProposalExecution, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		o := opts()
+		o.StopTimeout = 50 * time.Millisecond
+		_, report, err := Execute(ctx, c, c.Rebalance, api.RebalanceRequestWithDefaults(), o)
+		g.Expect(err).To(MatchError(ContainSubstring("timed out waiting for execution of user task %s to stop",
+			testUserTaskID)))
+		g.Expect(report.Aborted).To(BeTrue())
+		g.Expect(report.FinalState).To(BeNil())
+	})
+
+	t.Run("Reports user task finished before it could be stopped", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointRebalance, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, testUserTaskID, `{}`)
+		})
+		server.handle(api.EndpointUserTasks, userTasksHandler(func() types.UserTaskStatus {
+			if ctx.Err() == nil {
+				cancel()
+				return types.UserTaskStatusInExecution
+			}
+			return types.UserTaskStatusCompleted
+		}))
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		_, report, err := Execute(ctx, c, c.Rebalance, api.RebalanceRequestWithDefaults(), opts())
+		g.Expect(err).To(MatchError(context.Canceled))
+		g.Expect(err).To(MatchError(ContainSubstring("finished before its execution could be stopped")))
+		g.Expect(report.Aborted).To(BeFalse())
+		g.Expect(report.Status).To(Equal(types.UserTaskStatusCompleted))
+		g.Expect(server.count(api.EndpointStopProposalExecution)).To(BeZero())
+	})
+}
@@ -0,0 +1,300 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultFailoverRetryInterval = 30 * time.Second
+	UserTaskIDsQueryParam        = "user_task_ids"
+
+	// userTaskOwnerRetention is how long the server owning a user task is remembered which matches the default
+	// retention time of completed user tasks in Cruise Control.
+	userTaskOwnerRetention = 24 * time.Hour
+
+	userTaskContextKey userTaskContextKeyType = "CruiseControlUserTaskID"
+	serverContextKey   serverContextKeyType   = "CruiseControlServer"
+)
+
+type userTaskContextKeyType string
+
+type serverContextKeyType string
+
+// ContextWithUserTaskID returns a context which pins the requests made with it to the Cruise Control server
+// owning the user task if the client has multiple server URLs configured.
+func ContextWithUserTaskID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userTaskContextKey, id)
+}
+
+// UserTaskIDFromContext returns the user task ID set using ContextWithUserTaskID.
+func UserTaskIDFromContext(ctx context.Context) (string, bool) {
+	if v := ctx.Value(userTaskContextKey); v != nil {
+		if id, ok := v.(string); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// ServerStatus is the health of a Cruise Control server the client is configured with.
+type ServerStatus struct {
+	URL     string
+	Healthy bool
+	// LastError is the error of the latest failed request or health check.
+	LastError error
+	// FailedAt is the time of the latest failed request or health check.
+	FailedAt time.Time
+}
+
+type userTaskOwner struct {
+	server int
+	seen   time.Time
+}
+
+// serverPool holds the ordered list of Cruise Control servers and tracks their health and the user tasks they own.
+type serverPool struct {
+	mu            sync.Mutex
+	urls          []*url.URL
+	status        []ServerStatus
+	owners        map[string]userTaskOwner
+	retryInterval time.Duration
+}
+
+func newServerPool(serverURLs []string, retryInterval time.Duration) (*serverPool, error) {
+	if retryInterval <= 0 {
+		retryInterval = DefaultFailoverRetryInterval
+	}
+	p := &serverPool{
+		urls:          make([]*url.URL, 0, len(serverURLs)),
+		status:        make([]ServerStatus, 0, len(serverURLs)),
+		owners:        make(map[string]userTaskOwner),
+		retryInterval: retryInterval,
+	}
+	for _, s := range serverURLs {
+		if !strings.HasSuffix(s, "/") {
+			s += "/"
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Cruise Control server URL: %w", err)
+		}
+		p.urls = append(p.urls, u)
+		p.status = append(p.status, ServerStatus{URL: u.String(), Healthy: true})
+	}
+	return p, nil
+}
+
+// primary returns the URL of the first server.
+func (p *serverPool) primary() *url.URL {
+	return p.urls[0]
+}
+
+// candidates returns the servers to try in order: the healthy ones first followed by the unhealthy ones
+// which have not failed within the retry interval.
+func (p *serverPool) candidates(now time.Time) []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := make([]int, 0, len(p.urls))
+	retry := make([]int, 0)
+	for i, s := range p.status {
+		switch {
+		case s.Healthy:
+			healthy = append(healthy, i)
+		case now.Sub(s.FailedAt) >= p.retryInterval:
+			retry = append(retry, i)
+		}
+	}
+	candidates := append(healthy, retry...)
+	if len(candidates) == 0 {
+		// Every server failed recently, try them all in order instead of failing without sending the request.
+		for i := range p.urls {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
+func (p *serverPool) markFailed(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status[i].Healthy = false
+	p.status[i].LastError = err
+	p.status[i].FailedAt = time.Now()
+}
+
+func (p *serverPool) markHealthy(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status[i].Healthy = true
+}
+
+func (p *serverPool) statuses() []ServerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := make([]ServerStatus, len(p.status))
+	copy(s, p.status)
+	return s
+}
+
+func (p *serverPool) setOwner(id string, server int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if _, ok := p.owners[id]; !ok {
+		for k, o := range p.owners {
+			if now.Sub(o.seen) > userTaskOwnerRetention {
+				delete(p.owners, k)
+			}
+		}
+	}
+	p.owners[id] = userTaskOwner{server: server, seen: now}
+}
+
+func (p *serverPool) owner(id string) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	o, ok := p.owners[id]
+	return o.server, ok
+}
+
+// pinned returns the server the request needs to be sent to as it refers to user tasks owned by that server.
+func (p *serverPool) pinned(ctx context.Context, req *http.Request) (int, bool) {
+	if i, ok := ctx.Value(serverContextKey).(int); ok {
+		return i, true
+	}
+	if len(p.urls) == 1 {
+		return 0, false
+	}
+
+	ids := make([]string, 0)
+	if id := req.Header.Get(types.UserTaskIDHTTPHeader); id != "" {
+		ids = append(ids, id)
+	}
+	if id, ok := UserTaskIDFromContext(ctx); ok {
+		ids = append(ids, id)
+	}
+	if req.URL != nil {
+		if v := req.URL.Query().Get(UserTaskIDsQueryParam); v != "" {
+			ids = append(ids, strings.Split(v, ",")...)
+		}
+	}
+
+	server := -1
+	for _, id := range ids {
+		o, ok := p.owner(id)
+		if !ok {
+			continue
+		}
+		if server >= 0 && server != o {
+			// The user tasks are owned by different servers, there is no single server to pin the request to.
+			return 0, false
+		}
+		server = o
+	}
+	return server, server >= 0
+}
+
+// serverUnavailable returns true if the response indicates that the server is not able to serve requests. Cruise
+// Control reports ordinary request errors with 500 status code and an error body, so other server errors only count
+// if the body does not decode as an error returned by Cruise Control, e.g. if a proxy responded instead.
+func serverUnavailable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError && !isAPIError(resp)
+}
+
+// isAPIError returns true if the body of the response decodes as an error returned by Cruise Control. The body is
+// restored so that it can be read again.
+func isAPIError(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var apiErr types.APIError
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return false
+	}
+	return apiErr.ErrorMessage != "" || apiErr.Message != ""
+}
+
+// shouldFailover returns true if the request can be sent to the next server after receiving the response.
+// The server might have received the request even if a proxy responded instead, so only idempotent requests fail
+// over to avoid submitting the same operation to multiple servers.
+func shouldFailover(resp *http.Response) bool {
+	return resp.Request.Method == http.MethodGet && serverUnavailable(resp)
+}
+
+// canFailover returns true if the request which failed with the transport error can be sent to the next server.
+// Requests other than GET are only sent again if the connection to the server could not be established, as
+// otherwise the server might have received them.
+func canFailover(method string, err error) bool {
+	if method == http.MethodGet {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial" || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Servers returns the health of the Cruise Control servers the client is configured with.
+func (c *Client) Servers() []ServerStatus {
+	return c.servers.statuses()
+}
+
+// CheckServers checks the health of every Cruise Control server the client is configured with by requesting
+// their state and returns the result. Servers which become healthy are used again in the configured order.
+func (c *Client) CheckServers(ctx context.Context) []ServerStatus {
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateExecutor}
+
+	for i := range c.servers.urls {
+		_, err := c.State(context.WithValue(ctx, serverContextKey, i), req)
+		var transportErr *types.TransportError
+		var apiErr *types.CruiseControlError
+		switch {
+		case err == nil:
+			c.servers.markHealthy(i)
+		case errors.As(err, &transportErr), errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError:
+			c.servers.markFailed(i, err)
+		default:
+			// The server responded, the request failed for a different reason.
+			c.servers.markHealthy(i)
+		}
+	}
+	return c.Servers()
+}
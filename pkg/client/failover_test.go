@@ -0,0 +1,158 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+)
+
+func TestFailover(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, "", `{}`)
+	}
+	failing := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, status, "", `{"errorMessage":"failed"}`)
+		}
+	}
+	// proxyError responds like a proxy in front of Cruise Control would.
+	proxyError := func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "upstream connect error", http.StatusInternalServerError)
+	}
+	// dropping closes the connection after the request has been received.
+	dropping := func(w http.ResponseWriter, _ *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}
+
+	methods := []struct {
+		method string
+		send   func(ctx context.Context, c *Client) error
+	}{
+		{
+			method: http.MethodGet,
+			send: func(ctx context.Context, c *Client) error {
+				_, err := c.State(ctx, api.StateRequestWithDefaults())
+				return err
+			},
+		},
+		{
+			method: http.MethodPost,
+			send: func(ctx context.Context, c *Client) error {
+				_, err := c.Rebalance(ctx, api.RebalanceRequestWithDefaults())
+				return err
+			},
+		},
+	}
+
+	newServers := func(t *testing.T, primary http.HandlerFunc) (*fakeCruiseControl, *fakeCruiseControl) {
+		first, second := newFakeCruiseControl(t), newFakeCruiseControl(t)
+		first.handle(api.EndpointState, primary)
+		first.handle(api.EndpointRebalance, primary)
+		second.handle(api.EndpointState, ok)
+		second.handle(api.EndpointRebalance, ok)
+		return first, second
+	}
+
+	for _, m := range methods {
+		m := m
+		get := m.method == http.MethodGet
+
+		for _, test := range []struct {
+			name     string
+			handler  http.HandlerFunc
+			failover bool
+		}{
+			{name: "Cruise Control error", handler: failing(http.StatusInternalServerError)},
+			{name: "proxy error", handler: proxyError, failover: true},
+			{name: "status 502", handler: failing(http.StatusBadGateway), failover: true},
+			{name: "status 503", handler: failing(http.StatusServiceUnavailable), failover: true},
+			{name: "status 504", handler: failing(http.StatusGatewayTimeout), failover: true},
+		} {
+			test := test
+			t.Run(fmt.Sprintf("%s with %s", m.method, test.name), func(t *testing.T) {
+				g := NewGomegaWithT(t)
+
+				first, second := newServers(t, test.handler)
+				c := newTestClient(t, &Config{ServerURLs: []string{first.URL(), second.URL()}})
+
+				err := m.send(context.Background(), c)
+				g.Expect(c.Servers()[0].Healthy).To(Equal(!test.failover))
+				if get && test.failover {
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(second.count(api.EndpointState)).To(Equal(1))
+					return
+				}
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(second.count(api.EndpointState) + second.count(api.EndpointRebalance)).To(BeZero())
+			})
+		}
+
+		t.Run(m.method+" with connection refused", func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+			_, second := newServers(t, ok)
+			c := newTestClient(t, &Config{ServerURLs: []string{closed.URL + testServerPath, second.URL()}})
+
+			g.Expect(m.send(context.Background(), c)).To(Succeed())
+			g.Expect(second.count(api.EndpointState) + second.count(api.EndpointRebalance)).To(Equal(1))
+			g.Expect(c.Servers()[0].Healthy).To(BeFalse())
+		})
+
+		t.Run(m.method+" with connection dropped", func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			first, second := newServers(t, dropping)
+			c := newTestClient(t, &Config{ServerURLs: []string{first.URL(), second.URL()}})
+
+			err := m.send(context.Background(), c)
+			if get {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(second.count(api.EndpointState)).To(Equal(1))
+			} else {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(second.count(api.EndpointRebalance)).To(BeZero())
+			}
+			g.Expect(c.Servers()[0].Healthy).To(BeFalse())
+		})
+	}
+}
+
+func TestReadServerURLsFromEnvironment(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Setenv(ServerURLsEnvKey, " http://cc-0:8090/kafkacruisecontrol/ ,,http://cc-1:8090/kafkacruisecontrol/, ")
+
+	c := &Config{}
+	c.ReadFromEnvironment()
+	g.Expect(c.ServerURLs).To(Equal([]string{
+		"http://cc-0:8090/kafkacruisecontrol/",
+		"http://cc-1:8090/kafkacruisecontrol/",
+	}))
+}
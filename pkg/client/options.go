@@ -31,6 +31,7 @@ const (
 	HTTPHeaderAccept      = "Accept"
 	HTTPHeaderContentType = "Content-Type"
 	MIMETypeJSON          = "application/json"
+	MIMETypeTextPlain     = "text/plain"
 	ChartSetUTF8          = "utf-8"
 	JSONQueryParam        = "json"
 	ReasonQueryParam      = "reason"
@@ -114,6 +115,14 @@ func WithJSONQuery() RequestOptionApplier {
 	return WithQuery(JSONQueryParam, "true")
 }
 
+func WithAcceptText() RequestOptionApplier {
+	return WithHeader(HTTPHeaderAccept, MIMETypeTextPlain)
+}
+
+func WithTextQuery() RequestOptionApplier {
+	return WithQuery(JSONQueryParam, "false")
+}
+
 func WithContext(ctx context.Context) RequestOptionApplier {
 	return func(r *http.Request) error {
 		r2 := r.WithContext(ctx)
@@ -0,0 +1,169 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// ErrLimitWaitExceedsDeadline is returned if a request would need to wait for its endpoint rate limit
+// beyond the deadline of its context.
+var ErrLimitWaitExceedsDeadline = errors.New("waiting for rate limit would exceed context deadline")
+
+// EndpointLimit defines the rate and the concurrency limits of requests sent to an endpoint.
+type EndpointLimit struct {
+	// Rate is the number of requests per second allowed to be sent to the endpoint. Zero means no rate limit.
+	Rate float64
+	// Burst is the number of requests allowed to be sent at once. It defaults to 1 if Rate is set.
+	Burst int
+	// MaxInFlight is the number of concurrent requests allowed to the endpoint. Zero means no limit.
+	MaxInFlight int
+}
+
+// LimitWaitObserver is called with the time a request spent waiting for the limits of its endpoint.
+type LimitWaitObserver func(e types.APIEndpoint, wait time.Duration)
+
+// endpointLimiter is a token bucket rate limiter combined with a semaphore limiting the requests in flight.
+type endpointLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	inFlight chan struct{}
+}
+
+func newEndpointLimiter(l EndpointLimit) *endpointLimiter {
+	limiter := &endpointLimiter{
+		rate:  l.Rate,
+		burst: float64(l.Burst),
+	}
+	if limiter.burst < 1 {
+		limiter.burst = 1
+	}
+	limiter.tokens = limiter.burst
+	if l.MaxInFlight > 0 {
+		limiter.inFlight = make(chan struct{}, l.MaxInFlight)
+	}
+	return limiter
+}
+
+// reserve takes a token from the bucket and returns the time to wait until the token becomes available.
+func (l *endpointLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (l *endpointLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// wait blocks until the request is allowed to be sent by both the rate and the concurrency limit and returns
+// the function releasing the concurrency limit.
+func (l *endpointLimiter) wait(ctx context.Context) (func(), error) {
+	if l.rate > 0 {
+		if d := l.reserve(time.Now()); d > 0 {
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+				l.cancel()
+				return nil, ErrLimitWaitExceedsDeadline
+			}
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				l.cancel()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// endpointLimiters holds the limiters of the endpoints with limits configured.
+type endpointLimiters struct {
+	limiters map[types.APIEndpoint]*endpointLimiter
+	observer LimitWaitObserver
+}
+
+func newEndpointLimiters(limits map[types.APIEndpoint]EndpointLimit, observer LimitWaitObserver) *endpointLimiters {
+	l := &endpointLimiters{
+		limiters: make(map[types.APIEndpoint]*endpointLimiter, len(limits)),
+		observer: observer,
+	}
+	for e, limit := range limits {
+		if limit.Rate > 0 || limit.MaxInFlight > 0 {
+			l.limiters[e] = newEndpointLimiter(limit)
+		}
+	}
+	return l
+}
+
+// acquire waits for the limits of the endpoint and returns the function to be called once the request is done.
+func (l *endpointLimiters) acquire(ctx context.Context, e types.APIEndpoint) (func(), error) {
+	limiter, ok := l.limiters[e]
+	if !ok {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	release, err := limiter.wait(ctx)
+	wait := time.Since(start)
+
+	if l.observer != nil {
+		l.observer(e, wait)
+	}
+	if wait > time.Millisecond {
+		logr.FromContextOrDiscard(ctx).V(0).Info("waited for endpoint limits", "endpoint", e, "wait", wait)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for limits of endpoint %s: %w", e, err)
+	}
+	return release, nil
+}
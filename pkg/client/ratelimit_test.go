@@ -0,0 +1,201 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestEndpointLimiterReserve(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	l := newEndpointLimiter(EndpointLimit{Rate: 10, Burst: 2})
+	now := time.Now()
+
+	g.Expect(l.reserve(now)).To(BeZero())
+	g.Expect(l.reserve(now)).To(BeZero())
+	g.Expect(l.reserve(now)).To(Equal(100 * time.Millisecond))
+	g.Expect(l.reserve(now)).To(Equal(200 * time.Millisecond))
+
+	// Cancelled reservations are returned to the bucket.
+	l.cancel()
+	l.cancel()
+	g.Expect(l.reserve(now)).To(Equal(100 * time.Millisecond))
+
+	// The bucket is refilled up to the burst.
+	now = now.Add(time.Second)
+	g.Expect(l.reserve(now)).To(BeZero())
+	g.Expect(l.reserve(now)).To(BeZero())
+	g.Expect(l.reserve(now)).To(BeNumerically(">", 0))
+}
+
+func TestEndpointLimits(t *testing.T) {
+	newServer := func(t *testing.T) *fakeCruiseControl {
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		return server
+	}
+	state := func(ctx context.Context, c *Client) error {
+		_, err := c.State(ctx, api.StateRequestWithDefaults())
+		return err
+	}
+
+	t.Run("Rate limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var mu sync.Mutex
+		waits := make([]time.Duration, 0)
+		server := newServer(t)
+		c := newTestClient(t, &Config{
+			ServerURL:      server.URL(),
+			EndpointLimits: map[types.APIEndpoint]EndpointLimit{api.EndpointState: {Rate: 20}},
+			OnLimitWait: func(e types.APIEndpoint, wait time.Duration) {
+				g.Expect(e).To(Equal(api.EndpointState))
+				mu.Lock()
+				defer mu.Unlock()
+				waits = append(waits, wait)
+			},
+		})
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			g.Expect(state(context.Background(), c)).To(Succeed())
+		}
+		g.Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+		g.Expect(server.count(api.EndpointState)).To(Equal(3))
+		g.Expect(waits).To(HaveLen(3))
+		g.Expect(waits[0]).To(BeNumerically("<", 10*time.Millisecond))
+	})
+
+	t.Run("Endpoints without limits", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var observed atomic.Int32
+		server := newServer(t)
+		c := newTestClient(t, &Config{
+			ServerURL:      server.URL(),
+			EndpointLimits: map[types.APIEndpoint]EndpointLimit{api.EndpointRebalance: {Rate: 0.1}},
+			OnLimitWait:    func(types.APIEndpoint, time.Duration) { observed.Add(1) },
+		})
+
+		for i := 0; i < 3; i++ {
+			g.Expect(state(context.Background(), c)).To(Succeed())
+		}
+		g.Expect(observed.Load()).To(BeZero())
+	})
+
+	t.Run("Wait exceeding deadline", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newTestClient(t, &Config{
+			ServerURL:      server.URL(),
+			EndpointLimits: map[types.APIEndpoint]EndpointLimit{api.EndpointState: {Rate: 1}},
+		})
+		g.Expect(state(context.Background(), c)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := state(ctx, c)
+		g.Expect(errors.Is(err, ErrLimitWaitExceedsDeadline)).To(BeTrue())
+		g.Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+
+	t.Run("Concurrency limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var inFlight, maxInFlight atomic.Int32
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newTestClient(t, &Config{
+			ServerURL:      server.URL(),
+			EndpointLimits: map[types.APIEndpoint]EndpointLimit{api.EndpointState: {MaxInFlight: 2}},
+		})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 6)
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- state(context.Background(), c)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+		g.Expect(server.count(api.EndpointState)).To(Equal(6))
+		g.Expect(maxInFlight.Load()).To(Equal(int32(2)))
+	})
+
+	t.Run("Cancelled while waiting for concurrency limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		blocked := make(chan struct{})
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, _ *http.Request) {
+			<-blocked
+			writeJSON(w, http.StatusOK, "", `{}`)
+		})
+		c := newTestClient(t, &Config{
+			ServerURL:      server.URL(),
+			EndpointLimits: map[types.APIEndpoint]EndpointLimit{api.EndpointState: {MaxInFlight: 1}},
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- state(context.Background(), c) }()
+		g.Eventually(func() int { return server.count(api.EndpointState) }).Should(Equal(1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		g.Expect(errors.Is(state(ctx, c), context.DeadlineExceeded)).To(BeTrue())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+
+		close(blocked)
+		g.Expect(<-done).To(Succeed())
+	})
+}
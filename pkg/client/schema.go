@@ -0,0 +1,212 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const ResponseSchemaQueryParam = "get_response_schema"
+
+func WithResponseSchemaQuery() RequestOptionApplier {
+	return WithQuery(ResponseSchemaQueryParam, "true")
+}
+
+// schemaCache holds the response schemas keyed by schemaKey.
+type schemaCache struct {
+	mu      sync.RWMutex
+	schemas map[string]*types.JSONSchema
+}
+
+func (c *schemaCache) get(key string) (*types.JSONSchema, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.schemas[key]
+	return s, ok
+}
+
+func (c *schemaCache) set(key string, s *types.JSONSchema) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.schemas == nil {
+		c.schemas = make(map[string]*types.JSONSchema)
+	}
+	c.schemas[key] = s
+}
+
+// schemaKey returns the key of the response schema of the request sent to the endpoint. The schema depends on
+// the parameters of the request (e.g. verbose or substates), so the parameters which do not affect the result
+// are dropped and the rest is encoded in the key.
+func schemaKey(e types.APIEndpoint, r *http.Request) string {
+	if r.URL == nil {
+		return e.String()
+	}
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return strings.Join([]string{e.String(), r.URL.RawQuery}, " ")
+	}
+	for _, p := range []string{JSONQueryParam, ReasonQueryParam, ResponseSchemaQueryParam} {
+		query.Del(p)
+	}
+	return strings.Join([]string{e.String(), query.Encode()}, " ")
+}
+
+// ResponseSchema returns the JSON schema of the successful responses of the endpoint for the request. Schemas are
+// cached per endpoint and request parameters and updated from every response which carries one. If the schema is
+// not cached yet, the request is sent to Cruise Control, so use dry-run requests for endpoints which change
+// the state of the Kafka cluster.
+func (c *Client) ResponseSchema(ctx context.Context, e types.APIEndpoint, r interface{}) (*types.JSONSchema, error) {
+	req, err := MarshalRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	key := schemaKey(e, req)
+	if s, ok := c.schemas.get(key); ok {
+		return s, nil
+	}
+
+	resp := &api.TextResponse{}
+	err = c.do(ctx, r, resp, e, endpointMethod(e), MIMETypeJSON,
+		WithAcceptJSON(),
+		WithContentTypeJSON(),
+		WithJSONQuery(),
+		WithResponseSchemaQuery(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := c.schemas.get(key); ok {
+		return s, nil
+	}
+	return nil, errors.Errorf("no response schema returned for endpoint %s", e)
+}
+
+// processResponseSchema caches the schema returned in the response and compares it with the type of the result
+// if response schema checking is enabled.
+func (c Client) processResponseSchema(ctx context.Context, resp types.APIResponse, e types.APIEndpoint, key string) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	generic := genericResponseOf(resp)
+	if generic == nil || generic.ResponseSchema == "" || generic.StatusCode != http.StatusOK {
+		return
+	}
+
+	schema, err := types.ParseJSONSchema(generic.ResponseSchema)
+	if err != nil {
+		log.V(1).Info("failed to parse response schema", "endpoint", e, "error", err.Error())
+		return
+	}
+	c.schemas.set(key, schema)
+
+	if !c.responseSchemaCheck {
+		return
+	}
+	resultType, ok := resultTypeOf(resp)
+	if !ok {
+		return
+	}
+	diagnostics := types.CompareJSONSchema(schema, resultType)
+	if !diagnostics.Empty() {
+		log.V(0).Info("response schema does not match the expected type", "endpoint", e,
+			"unknown_fields", diagnostics.UnknownFields, "missing_fields", diagnostics.MissingFields)
+	}
+	generic.AddDiagnostics(diagnostics)
+}
+
+// genericResponseOf returns the embedded GenericResponse of the API response.
+func genericResponseOf(resp types.APIResponse) *types.GenericResponse {
+	v := reflect.Indirect(reflect.ValueOf(resp))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("GenericResponse")
+	if !f.IsValid() || !f.CanAddr() {
+		return nil
+	}
+	g, ok := f.Addr().Interface().(*types.GenericResponse)
+	if !ok {
+		return nil
+	}
+	return g
+}
+
+// decodedTypeOf returns the type the body of the API response was decoded into based on its status code.
+func decodedTypeOf(resp types.APIResponse, statusCode int) (reflect.Type, bool) {
+	switch statusCode {
+	case http.StatusOK:
+		return resultTypeOf(resp)
+	case http.StatusAccepted:
+		return reflect.TypeOf(types.ProgressResult{}), true
+	default:
+		return reflect.TypeOf(types.APIError{}), true
+	}
+}
+
+// processUnknownFields reports the fields of the response body which were dropped during decoding.
+func (c Client) processUnknownFields(ctx context.Context, resp types.APIResponse, e types.APIEndpoint, body []byte) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	generic := genericResponseOf(resp)
+	if generic == nil {
+		return
+	}
+	t, ok := decodedTypeOf(resp, generic.StatusCode)
+	if !ok {
+		return
+	}
+
+	unknown, err := types.UnknownJSONFields(body, t)
+	if err != nil {
+		log.V(1).Info("failed to check response for unknown fields", "endpoint", e, "error", err.Error())
+		return
+	}
+	if len(unknown) > 0 {
+		log.V(0).Info("response contains unknown fields", "endpoint", e, "unknown_fields", unknown)
+	}
+	generic.AddDiagnostics(&types.ResponseDiagnostics{UnknownFields: unknown})
+}
+
+// resultTypeOf returns the type of the Result field of the API response.
+func resultTypeOf(resp types.APIResponse) (reflect.Type, bool) {
+	v := reflect.Indirect(reflect.ValueOf(resp))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	f, ok := v.Type().FieldByName("Result")
+	if !ok {
+		return nil, false
+	}
+	return f.Type, true
+}
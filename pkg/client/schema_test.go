@@ -0,0 +1,110 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestSchemaKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key := func(rawQuery string) string {
+		r, err := http.NewRequest(http.MethodGet, "http://localhost/state?"+rawQuery, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		return schemaKey(api.EndpointState, r)
+	}
+
+	g.Expect(key("verbose=true")).NotTo(Equal(key("verbose=false")))
+	g.Expect(key("substates=EXECUTOR")).NotTo(Equal(key("substates=MONITOR")))
+	g.Expect(key("verbose=true&json=true&reason=test&get_response_schema=true")).To(Equal(key("verbose=true")))
+	g.Expect(key("substates=EXECUTOR&verbose=true")).To(Equal(key("verbose=true&substates=EXECUTOR")))
+}
+
+func TestResponseSchema(t *testing.T) {
+	const (
+		schema        = `{"type": "object", "properties": {"ExecutorState": {"type": "object", "properties": {}}}}`
+		verboseSchema = `{"type": "object", "properties": {"ExecutorState": {"type": "object", "properties": {}}, "Extra": {"type": "number"}}}` //nolint:lll
+	)
+
+	newServer := func(t *testing.T) *fakeCruiseControl {
+		server := newFakeCruiseControl(t)
+		server.handle(api.EndpointState, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get(ResponseSchemaQueryParam) == "true" {
+				s := schema
+				if r.URL.Query().Get("verbose") == "true" {
+					s = verboseSchema
+				}
+				w.Header().Set(types.JSONSchemaHTTPHeader, s)
+			}
+			writeJSON(w, http.StatusOK, "", `{"ExecutorState":{"state":"NO_TASK_IN_PROGRESS"}}`)
+		})
+		return server
+	}
+
+	t.Run("Schemas are cached per request parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+
+		req := api.StateRequestWithDefaults()
+		req.Verbose = false
+		s, err := c.ResponseSchema(context.Background(), api.EndpointState, req)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(s.Properties).NotTo(HaveKey("Extra"))
+
+		verbose := api.StateRequestWithDefaults()
+		verbose.Verbose = true
+		s, err = c.ResponseSchema(context.Background(), api.EndpointState, verbose)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(s.Properties).To(HaveKey("Extra"))
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+
+		s, err = c.ResponseSchema(context.Background(), api.EndpointState, req)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(s.Properties).NotTo(HaveKey("Extra"))
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+
+	t.Run("Schema check reports differences", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t)
+		c := newTestClient(t, &Config{ServerURL: server.URL(), ResponseSchemaCheck: true})
+
+		req := api.StateRequestWithDefaults()
+		req.Verbose = true
+		resp, err := c.State(context.Background(), req)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resp.Diagnostics).NotTo(BeNil())
+		g.Expect(resp.Diagnostics.UnknownFields).To(ContainElement("$.Extra"))
+
+		// The schema is cached from the response of the request.
+		s, err := c.ResponseSchema(context.Background(), api.EndpointState, req)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(s.Properties).To(HaveKey("Extra"))
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+	})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const sessionContextKey sessionContextKeyType = "CruiseControlSession"
+
+type sessionContextKeyType string
+
+// Session holds the session cookies of Cruise Control for a logical operation. Cruise Control associates user tasks
+// with sessions, so repeating a request within the same session returns the result of the existing user task
+// instead of creating a new one.
+type Session struct {
+	mu  sync.Mutex
+	jar http.CookieJar
+	// tasks holds the key of the request which created each user task of the session.
+	tasks    map[string]string
+	lastDate time.Time
+}
+
+// NewSession returns a new empty Session.
+func NewSession() *Session {
+	s := &Session{
+		tasks: make(map[string]string),
+	}
+	s.Reset()
+	return s
+}
+
+// ContextWithSession returns a context which makes the requests sent with it share a new Session.
+func ContextWithSession(ctx context.Context) context.Context {
+	return ContextWithExistingSession(ctx, NewSession())
+}
+
+// ContextWithExistingSession returns a context which makes the requests sent with it use the provided Session.
+func ContextWithExistingSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey, s)
+}
+
+// SessionFromContext returns the Session of the context.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	if v := ctx.Value(sessionContextKey); v != nil {
+		if s, ok := v.(*Session); ok && s != nil {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Reset drops the session cookies so the next request starts a new session.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// cookiejar.New never returns an error without options.
+	s.jar, _ = cookiejar.New(nil)
+}
+
+// apply adds the session cookies to the request.
+func (s *Session) apply(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.jar.Cookies(r.URL) {
+		r.AddCookie(c)
+	}
+}
+
+// update stores the session cookies set by the response.
+func (s *Session) update(u *url.URL, resp *http.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		s.jar.SetCookies(u, cookies)
+	}
+}
+
+// stale returns true if the response belongs to a user task created by a different request of the session or
+// it is older than a previous response of the session. Otherwise, it records the response.
+func (s *Session) stale(key string, resp *http.Response) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := resp.Header.Get(types.UserTaskIDHTTPHeader)
+	if owner, ok := s.tasks[id]; ok && id != "" && owner != key {
+		return true
+	}
+
+	date, err := http.ParseTime(resp.Header.Get(types.DateHTTPHeader))
+	if err == nil {
+		if date.Before(s.lastDate) {
+			return true
+		}
+		s.lastDate = date
+	}
+	if id != "" {
+		s.tasks[id] = key
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const testSessionCookie = "JSESSIONID"
+
+func TestSessionStale(t *testing.T) {
+	response := func(id string, date time.Time) *http.Response {
+		rec := httptest.NewRecorder()
+		if id != "" {
+			rec.Header().Set(types.UserTaskIDHTTPHeader, id)
+		}
+		if !date.IsZero() {
+			rec.Header().Set(types.DateHTTPHeader, date.UTC().Format(http.TimeFormat))
+		}
+		return rec.Result()
+	}
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("User task of another request", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := NewSession()
+		g.Expect(s.stale("a", response("task-1", time.Time{}))).To(BeFalse())
+		g.Expect(s.stale("a", response("task-1", time.Time{}))).To(BeFalse())
+		g.Expect(s.stale("b", response("task-1", time.Time{}))).To(BeTrue())
+		g.Expect(s.stale("b", response("task-2", time.Time{}))).To(BeFalse())
+	})
+
+	t.Run("Response older than a previous one", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		s := NewSession()
+		g.Expect(s.stale("a", response("", now))).To(BeFalse())
+		g.Expect(s.stale("b", response("", now))).To(BeFalse())
+		g.Expect(s.stale("c", response("", now.Add(-time.Minute)))).To(BeTrue())
+		g.Expect(s.stale("c", response("", now.Add(time.Minute)))).To(BeFalse())
+	})
+}
+
+func TestSessionStaleResponse(t *testing.T) {
+	// newServer returns a server which creates a new session for requests without a session cookie and returns the
+	// user task of the first request of the session for every request sent with the cookie, like Cruise Control
+	// does when a session is reused for a different request.
+	newServer := func(t *testing.T, alwaysStale bool) *fakeCruiseControl {
+		server := newFakeCruiseControl(t)
+		sessions := 0
+		server.handle(api.EndpointState, func(w http.ResponseWriter, r *http.Request) {
+			if _, err := r.Cookie(testSessionCookie); err == nil || alwaysStale {
+				writeJSON(w, http.StatusOK, "task-1", `{}`)
+				return
+			}
+			sessions++
+			http.SetCookie(w, &http.Cookie{Name: testSessionCookie, Value: "session", Path: "/"})
+			taskID := "task-1"
+			if sessions > 1 {
+				taskID = "task-2"
+			}
+			writeJSON(w, http.StatusOK, taskID, `{}`)
+		})
+		return server
+	}
+	requests := func() (*api.StateRequest, *api.StateRequest) {
+		executor := api.StateRequestWithDefaults()
+		executor.Substates = []types.Substate{types.SubstateExecutor}
+		return api.StateRequestWithDefaults(), executor
+	}
+
+	t.Run("Stale response is sent again in a new session", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t, false)
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+		ctx := ContextWithSession(context.Background())
+		first, second := requests()
+
+		_, err := c.State(ctx, first)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(server.count(api.EndpointState)).To(Equal(1))
+
+		resp, err := c.State(ctx, second)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resp.TaskID).To(Equal("task-2"))
+		g.Expect(server.count(api.EndpointState)).To(Equal(3))
+	})
+
+	t.Run("Request is retried only once", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t, true)
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+		ctx := ContextWithSession(context.Background())
+		first, second := requests()
+
+		_, err := c.State(ctx, first)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.State(ctx, second)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(server.count(api.EndpointState)).To(Equal(3))
+	})
+
+	t.Run("Repeated request is not stale", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		server := newServer(t, false)
+		c := newTestClient(t, &Config{ServerURL: server.URL()})
+		ctx := ContextWithSession(context.Background())
+		first, _ := requests()
+
+		for i := 0; i < 2; i++ {
+			resp, err := c.State(ctx, first)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(resp.TaskID).To(Equal("task-1"))
+		}
+		g.Expect(server.count(api.EndpointState)).To(Equal(2))
+	})
+}
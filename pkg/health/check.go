@@ -0,0 +1,550 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultMinMonitoringCoveragePct = 95.0
+	DefaultWarningPenalty           = 10
+	DefaultCriticalPenalty          = 25
+)
+
+// Client is implemented by clients which are able to retrieve the state, the Kafka cluster state
+// and the cluster load from Cruise Control.
+type Client interface {
+	State(ctx context.Context, r *api.StateRequest) (*api.StateResponse, error)
+	KafkaClusterState(ctx context.Context, r *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error)
+	KafkaClusterLoad(ctx context.Context, r *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error)
+}
+
+// Config contains the configuration parameters for the health check.
+type Config struct {
+	// MinMonitoringCoveragePct is the monitoring coverage of the load monitor below which a warning is reported.
+	// DefaultMinMonitoringCoveragePct is used if not set.
+	MinMonitoringCoveragePct float64
+	// WarningPenalty is subtracted from the score for every warning. DefaultWarningPenalty is used if not set.
+	WarningPenalty int
+	// CriticalPenalty is subtracted from the score for every critical finding.
+	// DefaultCriticalPenalty is used if not set.
+	CriticalPenalty int
+}
+
+func (c Config) withDefaults() Config {
+	if c.MinMonitoringCoveragePct <= 0 {
+		c.MinMonitoringCoveragePct = DefaultMinMonitoringCoveragePct
+	}
+	if c.WarningPenalty <= 0 {
+		c.WarningPenalty = DefaultWarningPenalty
+	}
+	if c.CriticalPenalty <= 0 {
+		c.CriticalPenalty = DefaultCriticalPenalty
+	}
+	return c
+}
+
+// Check retrieves the state, the Kafka cluster state and the cluster load from Cruise Control and returns
+// the health report of the cluster. The cluster load is reported as missing instead of failing the check
+// if Cruise Control is not ready to serve it yet.
+func Check(ctx context.Context, client Client, config Config) (*Report, error) {
+	state, err := client.State(ctx, api.StateRequestWithDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Cruise Control state: %w", err)
+	}
+
+	clusterState, err := client.KafkaClusterState(ctx, api.KafkaClusterStateRequestWithDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster state: %w", err)
+	}
+
+	loadReq := api.KafkaClusterLoadRequestWithDefaults()
+	loadReq.PopulateDiskInfo = true
+	var load *types.BrokerStats
+	loadResp, err := client.KafkaClusterLoad(ctx, loadReq)
+	var notReady *types.NotReadyError
+	switch {
+	case errors.As(err, &notReady):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get Kafka cluster load: %w", err)
+	default:
+		load = loadResp.Result
+	}
+
+	return Evaluate(state.Result, clusterState.Result, load, config), nil
+}
+
+// Evaluate returns the health report for the provided Cruise Control responses. Any of them can be nil
+// in which case the checks depending on it are skipped and a warning is reported about the missing data.
+func Evaluate(state *types.StateResult, clusterState *types.KafkaClusterState, load *types.BrokerStats,
+	config Config,
+) *Report {
+	config = config.withDefaults()
+
+	report := &Report{
+		Time:     time.Now(),
+		Findings: make([]Finding, 0),
+	}
+
+	if clusterState != nil {
+		report.Findings = append(report.Findings, partitionFindings(clusterState.KafkaPartitionState)...)
+	} else {
+		report.Findings = append(report.Findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryPartitions,
+			Message:  "Kafka cluster state is not available, partition and log dir states are not checked",
+			Remediation: Remediation{
+				Endpoint: api.EndpointKafkaClusterState,
+				Hint:     "check that Cruise Control is able to connect to the Kafka cluster with KAFKA_CLUSTER_STATE",
+			},
+		})
+	}
+	if load != nil {
+		report.Findings = append(report.Findings, brokerFindings(load)...)
+	} else {
+		report.Findings = append(report.Findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryBrokers,
+			Message:  "cluster load is not available, broker and disk states are not checked",
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "wait for the load monitor to collect enough metric windows and check its progress with STATE",
+			},
+		})
+	}
+	report.Findings = append(report.Findings, diskFindings(clusterState, load)...)
+	if state != nil {
+		report.Findings = append(report.Findings, loadMonitorFindings(state.MonitorState, config)...)
+		report.Findings = append(report.Findings, analyzerFindings(state.AnalyzerState)...)
+		report.Findings = append(report.Findings, anomalyFindings(state.AnomalyDetectorState)...)
+	} else {
+		report.Findings = append(report.Findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryLoadMonitor,
+			Message:  "Cruise Control state is not available, load monitor, analyzer and anomalies are not checked",
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "check that Cruise Control is running and serves the STATE endpoint",
+			},
+		})
+	}
+
+	report.finalize(map[types.Severity]int{
+		types.SeverityWarning:  config.WarningPenalty,
+		types.SeverityCritical: config.CriticalPenalty,
+	})
+	return report
+}
+
+func partitionFindings(s types.KafkaPartitionState) []Finding {
+	findings := make([]Finding, 0)
+
+	if len(s.Offline) > 0 {
+		findings = append(findings, Finding{
+			Severity:   types.SeverityCritical,
+			Category:   CategoryPartitions,
+			Message:    fmt.Sprintf("%d partition(s) are offline", len(s.Offline)),
+			Brokers:    brokersOf(s.Offline, func(p types.PartitionState) []int32 { return p.Replicas }),
+			Partitions: partitionsOf(s.Offline),
+			Remediation: Remediation{
+				Endpoint: api.EndpointFixOfflineReplicas,
+				Hint:     "restore the brokers hosting the partitions or move their replicas with FIX_OFFLINE_REPLICAS",
+			},
+		})
+	}
+
+	if len(s.WithOfflineReplicas) > 0 {
+		findings = append(findings, Finding{
+			Severity:   types.SeverityCritical,
+			Category:   CategoryPartitions,
+			Message:    fmt.Sprintf("%d partition(s) have offline replicas", len(s.WithOfflineReplicas)),
+			Brokers:    brokersOf(s.WithOfflineReplicas, func(p types.PartitionState) []int32 { return p.OfflineReplicas }),
+			Partitions: partitionsOf(s.WithOfflineReplicas),
+			Remediation: Remediation{
+				Endpoint: api.EndpointFixOfflineReplicas,
+				Hint:     "move the offline replicas to healthy brokers and disks with FIX_OFFLINE_REPLICAS",
+			},
+		})
+	}
+
+	if len(s.UnderMinISR) > 0 {
+		remediation := Remediation{
+			Endpoint: api.EndpointKafkaClusterState,
+			Hint:     "producers using acks=all are failing, inspect the out-of-sync replicas with KAFKA_CLUSTER_STATE",
+		}
+		if len(brokersOf(s.UnderMinISR, func(p types.PartitionState) []int32 { return p.OfflineReplicas })) > 0 {
+			remediation = Remediation{
+				Endpoint: api.EndpointFixOfflineReplicas,
+				Hint:     "producers using acks=all are failing, move the offline replicas with FIX_OFFLINE_REPLICAS",
+			}
+		}
+		findings = append(findings, Finding{
+			Severity:    types.SeverityCritical,
+			Category:    CategoryPartitions,
+			Message:     fmt.Sprintf("%d partition(s) are under min ISR", len(s.UnderMinISR)),
+			Brokers:     brokersOf(s.UnderMinISR, func(p types.PartitionState) []int32 { return p.OutOfSyncReplicas }),
+			Partitions:  partitionsOf(s.UnderMinISR),
+			Remediation: remediation,
+		})
+	}
+
+	if len(s.UnderReplicatedPartitions) > 0 {
+		findings = append(findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryPartitions,
+			Message:  fmt.Sprintf("%d partition(s) are under-replicated", len(s.UnderReplicatedPartitions)),
+			Brokers: brokersOf(s.UnderReplicatedPartitions,
+				func(p types.PartitionState) []int32 { return p.OutOfSyncReplicas }),
+			Partitions: partitionsOf(s.UnderReplicatedPartitions),
+			Remediation: Remediation{
+				Endpoint: api.EndpointKafkaClusterState,
+				Hint:     "inspect the out-of-sync replicas with KAFKA_CLUSTER_STATE and check the health of the lagging brokers",
+			},
+		})
+	}
+
+	return findings
+}
+
+func brokerFindings(load *types.BrokerStats) []Finding {
+	findings := make([]Finding, 0)
+
+	dead := make([]int32, 0)
+	for _, b := range load.Brokers {
+		if b.BrokerState == types.BrokerStateDead {
+			dead = append(dead, b.Broker)
+		}
+	}
+	sortBrokers(dead)
+
+	if len(dead) > 0 {
+		findings = append(findings, Finding{
+			Severity: types.SeverityCritical,
+			Category: CategoryBrokers,
+			Message:  fmt.Sprintf("%d broker(s) are dead", len(dead)),
+			Brokers:  dead,
+			Remediation: Remediation{
+				Endpoint: api.EndpointRemoveBroker,
+				Hint:     "restart the brokers or move their replicas to healthy brokers with REMOVE_BROKER",
+			},
+		})
+	}
+	return findings
+}
+
+// diskFindings reports the offline log directories and dead disks of the brokers using both the Kafka cluster
+// state and the cluster load so that the same disk is only reported once.
+func diskFindings(clusterState *types.KafkaClusterState, load *types.BrokerStats) []Finding {
+	badDirs := make(map[int32]map[string]bool)
+	addDir := func(broker int32, dir string) {
+		if badDirs[broker] == nil {
+			badDirs[broker] = make(map[string]bool)
+		}
+		if dir != "" {
+			badDirs[broker][dir] = true
+		}
+	}
+
+	if clusterState != nil {
+		for id, dirs := range clusterState.KafkaBrokerState.OfflineLogDirsByBrokerID {
+			broker, err := strconv.ParseInt(id, 10, 32)
+			if err != nil || len(dirs) == 0 {
+				continue
+			}
+			for _, dir := range dirs {
+				addDir(int32(broker), dir)
+			}
+		}
+	}
+	if load != nil {
+		for _, b := range load.Brokers {
+			if b.BrokerState == types.BrokerStateBadDisks {
+				addDir(b.Broker, "")
+			}
+			for dir, stats := range b.DiskState {
+				if stats.DiskMB.Dead || stats.DiskPct.Dead {
+					addDir(b.Broker, dir)
+				}
+			}
+		}
+	}
+
+	brokers := make([]int32, 0, len(badDirs))
+	for broker := range badDirs {
+		brokers = append(brokers, broker)
+	}
+	sortBrokers(brokers)
+
+	findings := make([]Finding, 0, len(brokers))
+	for _, broker := range brokers {
+		dirs := make([]string, 0, len(badDirs[broker]))
+		for dir := range badDirs[broker] {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+
+		msg := fmt.Sprintf("broker %d has bad disks", broker)
+		if len(dirs) > 0 {
+			msg = fmt.Sprintf("broker %d has offline log directories: %s", broker, strings.Join(dirs, ", "))
+		}
+		findings = append(findings, Finding{
+			Severity: types.SeverityCritical,
+			Category: CategoryDisks,
+			Message:  msg,
+			Brokers:  []int32{broker},
+			Remediation: Remediation{
+				Endpoint: api.EndpointFixOfflineReplicas,
+				Hint:     "replace the failed disks or move the offline replicas to healthy disks with FIX_OFFLINE_REPLICAS",
+			},
+		})
+	}
+	return findings
+}
+
+func loadMonitorFindings(s types.LoadMonitorState, config Config) []Finding {
+	findings := make([]Finding, 0)
+
+	switch s.State {
+	case types.MonitorStatePaused:
+		msg := "load monitor sampling is paused"
+		if s.ReasonOfLatestPauseOrResume != "" {
+			msg = fmt.Sprintf("%s (reason: %s)", msg, s.ReasonOfLatestPauseOrResume)
+		}
+		findings = append(findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryLoadMonitor,
+			Message:  msg,
+			Remediation: Remediation{
+				Endpoint: api.EndpointResumeSampling,
+				Hint:     "resume metric sampling with RESUME_SAMPLING",
+			},
+		})
+	case types.MonitorStateBootstrapping, types.MonitorStateLoading, types.MonitorStateTraining:
+		findings = append(findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryLoadMonitor,
+			Message: fmt.Sprintf("load monitor is %s (bootstrap: %.1f%%, loading: %.1f%%, training: %.1f%%)",
+				s.State, s.BootstrapProgressPct, s.LoadingProgressPct, s.TrainingPercentage),
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "wait for the load monitor to finish and follow its progress with STATE",
+			},
+		})
+	case types.MonitorStateNotStarted, types.MonitorStateUndefined:
+		findings = append(findings, Finding{
+			Severity: types.SeverityCritical,
+			Category: CategoryLoadMonitor,
+			Message:  fmt.Sprintf("load monitor is not running (state: %s)", s.State),
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "check the Cruise Control logs and the load monitor state with STATE",
+			},
+		})
+	case types.MonitorStateRunning, types.MonitorStateSampling:
+	}
+
+	if s.Error != "" {
+		findings = append(findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryLoadMonitor,
+			Message:  fmt.Sprintf("load monitor reported an error: %s", s.Error),
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "check the Cruise Control logs and the load monitor state with STATE",
+			},
+		})
+	}
+
+	if s.MonitoringCoveragePercentage < config.MinMonitoringCoveragePct {
+		findings = append(findings, Finding{
+			Severity: types.SeverityWarning,
+			Category: CategoryLoadMonitor,
+			Message: fmt.Sprintf("monitoring coverage is %.1f%% (%.0f of %.0f partitions valid), expected at least %.1f%%",
+				s.MonitoringCoveragePercentage, s.NumValidPartitions, s.NumTotalPartitions,
+				config.MinMonitoringCoveragePct),
+			Remediation: Remediation{
+				Endpoint: api.EndpointState,
+				Hint:     "wait for more valid metric windows or check the metrics reporter of the brokers, follow with STATE",
+			},
+		})
+	}
+
+	return findings
+}
+
+func analyzerFindings(s types.AnalyzerState) []Finding {
+	notReady := make([]string, 0)
+	for _, g := range s.GoalReadiness {
+		if g.Status != types.GoalReadinessStatusReady {
+			notReady = append(notReady, g.Name.String())
+		}
+	}
+
+	if s.IsProposalReady && len(notReady) == 0 {
+		return nil
+	}
+
+	f := Finding{
+		Severity: types.SeverityInfo,
+		Category: CategoryAnalyzer,
+		Message:  fmt.Sprintf("goals are not ready: %s", strings.Join(notReady, ", ")),
+		Remediation: Remediation{
+			Endpoint: api.EndpointState,
+			Hint:     "wait for the load monitor to collect the metric windows required by the goals, follow with STATE",
+		},
+	}
+	if !s.IsProposalReady {
+		f.Severity = types.SeverityWarning
+		f.Message = "proposals are not ready"
+		if len(notReady) > 0 {
+			f.Message = fmt.Sprintf("%s, goals not ready: %s", f.Message, strings.Join(notReady, ", "))
+		}
+	}
+	return []Finding{f}
+}
+
+func anomalyFindings(s types.AnomalyDetectorState) []Finding {
+	findings := make([]Finding, 0)
+
+	byType := s.AnomaliesByType()
+	anomalyTypes := make([]types.AnomalyType, 0, len(byType))
+	for t := range byType {
+		anomalyTypes = append(anomalyTypes, t)
+	}
+	sort.Slice(anomalyTypes, func(i, j int) bool { return anomalyTypes[i] < anomalyTypes[j] })
+
+	for _, t := range anomalyTypes {
+		var ongoing, failedToStart int
+		for _, a := range byType[t] {
+			switch a.Status {
+			case types.AnomalyStatusDetected, types.AnomalyStatusCheckWithDelay,
+				types.AnomalyStatusLoadMonitorNotReady, types.AnomalyStatusCompletenessNotReady:
+				ongoing++
+			case types.AnomalyStatusFixFailedToStart:
+				ongoing++
+				failedToStart++
+			case types.AnomalyStatusIgnored, types.AnomalyStatusFixStarted, types.AnomalyStatusUndefined:
+			}
+		}
+		if ongoing == 0 {
+			continue
+		}
+
+		severity := types.SeverityWarning
+		if t == types.AnomalyTypeBrokerFailure || t == types.AnomalyTypeDiskFailure || failedToStart > 0 {
+			severity = types.SeverityCritical
+		}
+		msg := fmt.Sprintf("%d unresolved %s anomalies", ongoing, t)
+		if failedToStart > 0 {
+			msg = fmt.Sprintf("%s, self-healing failed to start for %d", msg, failedToStart)
+		}
+		findings = append(findings, Finding{
+			Severity:    severity,
+			Category:    CategoryAnomalies,
+			Message:     msg,
+			Remediation: anomalyRemediation(t),
+		})
+	}
+
+	if s.OngoingSelfHealingAnomaly != types.AnomalyTypeUndefined {
+		findings = append(findings, Finding{
+			Severity: types.SeverityInfo,
+			Category: CategoryAnomalies,
+			Message:  fmt.Sprintf("self-healing is in progress for %s anomaly", s.OngoingSelfHealingAnomaly),
+			Remediation: Remediation{
+				Endpoint: api.EndpointUserTasks,
+				Hint:     "follow the self-healing task with USER_TASKS",
+			},
+		})
+	}
+
+	return findings
+}
+
+func anomalyRemediation(t types.AnomalyType) Remediation {
+	switch t {
+	case types.AnomalyTypeGoalViolation:
+		return Remediation{
+			Endpoint: api.EndpointRebalance,
+			Hint:     "rebalance the cluster with REBALANCE or enable self-healing for goal violations",
+		}
+	case types.AnomalyTypeBrokerFailure:
+		return Remediation{
+			Endpoint: api.EndpointRemoveBroker,
+			Hint:     "restart the failed brokers or remove them with REMOVE_BROKER",
+		}
+	case types.AnomalyTypeMetricAnomaly:
+		return Remediation{
+			Endpoint: api.EndpointDemoteBroker,
+			Hint:     "move leadership away from the slow brokers with DEMOTE_BROKER",
+		}
+	case types.AnomalyTypeDiskFailure:
+		return Remediation{
+			Endpoint: api.EndpointFixOfflineReplicas,
+			Hint:     "move the replicas of the failed disks with FIX_OFFLINE_REPLICAS",
+		}
+	case types.AnomalyTypeTopicAnomaly:
+		return Remediation{
+			Endpoint: api.EndpointTopicConfiguration,
+			Hint:     "fix the replication factor of the topics with TOPIC_CONFIGURATION",
+		}
+	case types.AnomalyTypeMaintenanceEvent, types.AnomalyTypeUndefined:
+		fallthrough
+	default:
+		return Remediation{
+			Endpoint: api.EndpointState,
+			Hint:     "check the details of the anomalies with STATE",
+		}
+	}
+}
+
+func partitionsOf(partitions []types.PartitionState) []string {
+	result := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		result = append(result, fmt.Sprintf("%s-%d", p.Topic, p.Partition))
+	}
+	return result
+}
+
+func brokersOf(partitions []types.PartitionState, f func(types.PartitionState) []int32) []int32 {
+	seen := make(map[int32]bool)
+	brokers := make([]int32, 0)
+	for _, p := range partitions {
+		for _, b := range f(p) {
+			if !seen[b] {
+				seen[b] = true
+				brokers = append(brokers, b)
+			}
+		}
+	}
+	sortBrokers(brokers)
+	return brokers
+}
+
+func sortBrokers(brokers []int32) {
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i] < brokers[j] })
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Run("Healthy cluster", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := &types.StateResult{
+			MonitorState: types.LoadMonitorState{
+				State:                        types.MonitorStateRunning,
+				MonitoringCoveragePercentage: 100,
+			},
+			AnalyzerState: types.AnalyzerState{IsProposalReady: true},
+		}
+		load := &types.BrokerStats{
+			Brokers: []types.BrokerLoadStats{{Broker: 0, BrokerState: types.BrokerStateAlive}},
+		}
+
+		report := Evaluate(state, &types.KafkaClusterState{}, load, Config{})
+
+		g.Expect(report.Findings).To(BeEmpty())
+		g.Expect(report.Score).To(Equal(100))
+		g.Expect(report.Healthy()).To(BeTrue())
+	})
+
+	t.Run("Unhealthy cluster", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := &types.StateResult{
+			MonitorState: types.LoadMonitorState{
+				State:                        types.MonitorStateRunning,
+				MonitoringCoveragePercentage: 80,
+			},
+			AnalyzerState: types.AnalyzerState{IsProposalReady: true},
+			AnomalyDetectorState: types.AnomalyDetectorState{
+				RecentBrokerFailures: []types.AnomalyDetails{
+					{AnomalyID: "a", Status: types.AnomalyStatusDetected},
+				},
+			},
+		}
+		clusterState := &types.KafkaClusterState{
+			KafkaBrokerState: types.KafkaBrokerState{
+				OfflineLogDirsByBrokerID: map[string][]string{"1": {"/data/1"}, "2": {}},
+			},
+			KafkaPartitionState: types.KafkaPartitionState{
+				WithOfflineReplicas: []types.PartitionState{
+					{Topic: "foo", Partition: 0, OfflineReplicas: []int32{2}},
+				},
+				UnderReplicatedPartitions: []types.PartitionState{
+					{Topic: "foo", Partition: 0, OutOfSyncReplicas: []int32{2}},
+				},
+			},
+		}
+		load := &types.BrokerStats{
+			Brokers: []types.BrokerLoadStats{
+				{Broker: 1, BrokerState: types.BrokerStateBadDisks, DiskState: map[string]types.DiskStats{
+					"/data/1": {DiskMB: types.DiskUsageStat{Dead: true}},
+				}},
+				{Broker: 2, BrokerState: types.BrokerStateDead},
+			},
+		}
+
+		report := Evaluate(state, clusterState, load, Config{})
+
+		g.Expect(report.Status).To(Equal(StatusUnhealthy))
+		g.Expect(report.Findings[0].Severity).To(Equal(types.SeverityCritical))
+
+		endpoints := make([]types.APIEndpoint, 0)
+		for _, f := range report.Findings {
+			endpoints = append(endpoints, f.Remediation.Endpoint)
+		}
+		g.Expect(endpoints).To(ConsistOf(
+			api.EndpointFixOfflineReplicas,
+			api.EndpointRemoveBroker,
+			api.EndpointFixOfflineReplicas,
+			api.EndpointRemoveBroker,
+			api.EndpointKafkaClusterState,
+			api.EndpointState,
+		))
+		g.Expect(report.FindingsBySeverity(types.SeverityCritical)).To(HaveLen(4))
+		g.Expect(report.Score).To(Equal(0))
+	})
+
+	t.Run("Missing cluster load", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report := Evaluate(healthyState(), &types.KafkaClusterState{}, nil, Config{})
+
+		g.Expect(report.Findings).To(HaveLen(1))
+		g.Expect(report.Status).To(Equal(StatusDegraded))
+		g.Expect(report.Score).To(Equal(100 - DefaultWarningPenalty))
+	})
+
+	t.Run("Missing responses", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report := Evaluate(nil, nil, nil, Config{})
+
+		g.Expect(report.Findings).To(HaveLen(3))
+		g.Expect(report.Healthy()).To(BeFalse())
+		g.Expect(report.Score).To(Equal(100 - 3*DefaultWarningPenalty))
+	})
+}
+
+func healthyState() *types.StateResult {
+	return &types.StateResult{
+		MonitorState: types.LoadMonitorState{
+			State:                        types.MonitorStateRunning,
+			MonitoringCoveragePercentage: 100,
+		},
+		AnalyzerState: types.AnalyzerState{IsProposalReady: true},
+	}
+}
+
+type fakeClient struct {
+	state        *types.StateResult
+	clusterState *types.KafkaClusterState
+	load         *types.BrokerStats
+	loadErr      error
+}
+
+func (f *fakeClient) State(_ context.Context, _ *api.StateRequest) (*api.StateResponse, error) {
+	return &api.StateResponse{Result: f.state}, nil
+}
+
+func (f *fakeClient) KafkaClusterState(_ context.Context, _ *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error) { //nolint:lll
+	return &api.KafkaClusterStateResponse{Result: f.clusterState}, nil
+}
+
+func (f *fakeClient) KafkaClusterLoad(_ context.Context, r *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error) { //nolint:lll
+	if !r.PopulateDiskInfo {
+		return nil, errors.New("disk info is not requested")
+	}
+	if f.loadErr != nil {
+		return nil, f.loadErr
+	}
+	return &api.KafkaClusterLoadResponse{Result: f.load}, nil
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	load := &types.BrokerStats{
+		Brokers: []types.BrokerLoadStats{{Broker: 0, BrokerState: types.BrokerStateAlive}},
+	}
+
+	t.Run("Healthy cluster", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{state: healthyState(), clusterState: &types.KafkaClusterState{}, load: load}
+		report, err := Check(ctx, client, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Healthy()).To(BeTrue())
+	})
+
+	t.Run("Missing results", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report, err := Check(ctx, &fakeClient{load: load}, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Healthy()).To(BeFalse())
+
+		endpoints := make([]types.APIEndpoint, 0)
+		for _, f := range report.Findings {
+			endpoints = append(endpoints, f.Remediation.Endpoint)
+		}
+		g.Expect(endpoints).To(ConsistOf(api.EndpointKafkaClusterState, api.EndpointState))
+	})
+
+	t.Run("Cluster load not ready", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{
+			state:        healthyState(),
+			clusterState: &types.KafkaClusterState{},
+			loadErr:      &types.NotReadyError{CruiseControlError: &types.CruiseControlError{StatusCode: 500}},
+		}
+		report, err := Check(ctx, client, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Findings).To(HaveLen(1))
+		g.Expect(report.Findings[0].Category).To(Equal(CategoryBrokers))
+	})
+
+	t.Run("Failed request", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		client := &fakeClient{state: healthyState(), clusterState: &types.KafkaClusterState{}, loadErr: errors.New("boom")}
+		_, err := Check(ctx, client, Config{})
+		g.Expect(err).To(MatchError(ContainSubstring("boom")))
+	})
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"sort"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Category groups findings by the part of the cluster they are about.
+type Category string
+
+const (
+	CategoryPartitions  Category = "partitions"
+	CategoryBrokers     Category = "brokers"
+	CategoryDisks       Category = "disks"
+	CategoryLoadMonitor Category = "load-monitor"
+	CategoryAnalyzer    Category = "analyzer"
+	CategoryAnomalies   Category = "anomalies"
+)
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusDegraded  Status = "degraded"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Status summarizes the health of the cluster based on the most severe finding.
+type Status string
+
+// Remediation describes how a Finding can be resolved using Cruise Control.
+type Remediation struct {
+	// Endpoint of Cruise Control which can be used to resolve the finding.
+	Endpoint types.APIEndpoint `json:"endpoint"`
+	// Hint describes the remediation in human-readable form.
+	Hint string `json:"hint"`
+}
+
+// Finding is a single problem found during the health check.
+type Finding struct {
+	Severity    types.Severity `json:"severity"`
+	Category    Category       `json:"category"`
+	Message     string         `json:"message"`
+	Remediation Remediation    `json:"remediation"`
+	// Brokers affected by the finding.
+	Brokers []int32 `json:"brokers,omitempty"`
+	// Partitions affected by the finding in topic-partition format.
+	Partitions []string `json:"partitions,omitempty"`
+}
+
+// Report is the result of a health check.
+type Report struct {
+	Time time.Time `json:"time"`
+	// Score is between 0 (unhealthy) and 100 (healthy) calculated from the severity of the findings.
+	Score    int       `json:"score"`
+	Status   Status    `json:"status"`
+	Findings []Finding `json:"findings"`
+}
+
+// FindingsBySeverity returns the findings with the provided severity.
+func (r *Report) FindingsBySeverity(s types.Severity) []Finding {
+	findings := make([]Finding, 0)
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Healthy returns true if there are no warning or critical findings.
+func (r *Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+// finalize orders the findings by severity and calculates the score and the status of the report.
+func (r *Report) finalize(penalties map[types.Severity]int) {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return r.Findings[i].Severity > r.Findings[j].Severity
+	})
+
+	r.Score = 100
+	r.Status = StatusHealthy
+	for _, f := range r.Findings {
+		r.Score -= penalties[f.Severity]
+		switch {
+		case f.Severity == types.SeverityCritical:
+			r.Status = StatusUnhealthy
+		case f.Severity == types.SeverityWarning && r.Status == StatusHealthy:
+			r.Status = StatusDegraded
+		}
+	}
+	if r.Score < 0 {
+		r.Score = 0
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jbod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultMaxSpreadPct  = 20.0
+	DefaultNearlyFullPct = 85.0
+)
+
+const (
+	ActionNone Action = iota
+	ActionRebalanceDisk
+	ActionEvacuateLogDirs
+)
+
+// Action recommended for resolving the problems found by the analysis.
+type Action int8
+
+func (a Action) String() string {
+	switch a {
+	case ActionRebalanceDisk:
+		return "rebalance-disk"
+	case ActionEvacuateLogDirs:
+		return "evacuate-log-dirs"
+	case ActionNone:
+		fallthrough
+	default:
+		return "none"
+	}
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Client is implemented by clients which are able to retrieve the cluster load from Cruise Control.
+type Client interface {
+	KafkaClusterLoad(ctx context.Context, r *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error)
+}
+
+// Config contains the configuration parameters of the disk balance analysis.
+type Config struct {
+	// MaxSpreadPct is the maximum allowed difference between the usage of the most and the least used live log
+	// dirs of a broker in percentage points. DefaultMaxSpreadPct is used if not set.
+	MaxSpreadPct float64
+	// NearlyFullPct is the disk usage at or above which a log dir is considered nearly full.
+	// DefaultNearlyFullPct is used if not set.
+	NearlyFullPct float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxSpreadPct <= 0 {
+		c.MaxSpreadPct = DefaultMaxSpreadPct
+	}
+	if c.NearlyFullPct <= 0 {
+		c.NearlyFullPct = DefaultNearlyFullPct
+	}
+	return c
+}
+
+// LogDir is the usage of a log dir of a broker.
+type LogDir struct {
+	Broker     int32   `json:"broker"`
+	Path       string  `json:"path"`
+	UsageMB    float64 `json:"usageMB"`
+	UsagePct   float64 `json:"usagePct"`
+	Replicas   int32   `json:"replicas"`
+	Leaders    int32   `json:"leaders"`
+	Dead       bool    `json:"dead"`
+	NearlyFull bool    `json:"nearlyFull"`
+}
+
+// BrokerDisks is the disk balance of a broker. Usage statistics only include live log dirs.
+type BrokerDisks struct {
+	Broker  int32    `json:"broker"`
+	LogDirs []LogDir `json:"logDirs"`
+	MinPct  float64  `json:"minPct"`
+	MaxPct  float64  `json:"maxPct"`
+	MeanPct float64  `json:"meanPct"`
+	// SpreadPct is the difference between the usage of the most and the least used live log dirs.
+	SpreadPct  float64 `json:"spreadPct"`
+	Imbalanced bool    `json:"imbalanced"`
+	// NoHeadroom is true if the mean usage of the live log dirs is nearly full, so moving replicas between
+	// the log dirs of the broker cannot resolve the nearly full log dirs.
+	NoHeadroom bool `json:"noHeadroom"`
+}
+
+// Recommendation describes how the problems found by the analysis can be resolved using Cruise Control.
+type Recommendation struct {
+	Action Action `json:"action"`
+	// Reason describes the recommendation in human-readable form.
+	Reason string `json:"reason"`
+	// LogDirs to evacuate if the action is ActionEvacuateLogDirs.
+	LogDirs types.BrokerIDAndLogDirs `json:"logDirs,omitempty"`
+	// FixOfflineReplicas is the dry-run request moving the replicas off the dead log dirs if the action is
+	// ActionEvacuateLogDirs.
+	FixOfflineReplicas *api.FixOfflineReplicasRequest `json:"-"`
+	// Rebalance is the dry-run request balancing the disks within the brokers if the action is ActionRebalanceDisk.
+	Rebalance *api.RebalanceRequest `json:"-"`
+}
+
+// Report is the result of the disk balance analysis.
+type Report struct {
+	Brokers           []BrokerDisks  `json:"brokers"`
+	DeadLogDirs       []LogDir       `json:"deadLogDirs"`
+	NearlyFullLogDirs []LogDir       `json:"nearlyFullLogDirs"`
+	Recommendation    Recommendation `json:"recommendation"`
+
+	config Config
+}
+
+// ImbalancedBrokers returns the IDs of the brokers whose log dirs are imbalanced.
+func (r *Report) ImbalancedBrokers() []int32 {
+	brokers := make([]int32, 0)
+	for _, b := range r.Brokers {
+		if b.Imbalanced {
+			brokers = append(brokers, b.Broker)
+		}
+	}
+	return brokers
+}
+
+// Run retrieves the cluster load including the disk information from Cruise Control and analyzes the balance
+// of the log dirs of the brokers.
+func Run(ctx context.Context, client Client, config Config) (*Report, error) {
+	req := api.KafkaClusterLoadRequestWithDefaults()
+	req.PopulateDiskInfo = true
+
+	load, err := client.KafkaClusterLoad(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster load: %w", err)
+	}
+	return Analyze(load.Result, config)
+}
+
+// Analyze returns the disk balance of the brokers in the cluster load. It returns an error if the cluster load
+// does not contain disk information, see KafkaClusterLoadRequest.PopulateDiskInfo.
+func Analyze(load *types.BrokerStats, config Config) (*Report, error) {
+	config = config.withDefaults()
+	if load == nil || !hasDiskInfo(load) {
+		return nil, errors.New("cluster load does not contain disk information of the brokers")
+	}
+
+	report := &Report{
+		Brokers:           make([]BrokerDisks, 0, len(load.Brokers)),
+		DeadLogDirs:       make([]LogDir, 0),
+		NearlyFullLogDirs: make([]LogDir, 0),
+		config:            config,
+	}
+	for _, b := range load.Brokers {
+		disks := brokerDisks(b, config)
+		for _, d := range disks.LogDirs {
+			if d.Dead {
+				report.DeadLogDirs = append(report.DeadLogDirs, d)
+			}
+			if d.NearlyFull {
+				report.NearlyFullLogDirs = append(report.NearlyFullLogDirs, d)
+			}
+		}
+		report.Brokers = append(report.Brokers, disks)
+	}
+	sort.Slice(report.Brokers, func(i, j int) bool { return report.Brokers[i].Broker < report.Brokers[j].Broker })
+
+	report.Recommendation = report.recommend()
+	return report, nil
+}
+
+func hasDiskInfo(load *types.BrokerStats) bool {
+	for _, b := range load.Brokers {
+		if len(b.DiskState) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func brokerDisks(b types.BrokerLoadStats, config Config) BrokerDisks {
+	disks := BrokerDisks{
+		Broker:  b.Broker,
+		LogDirs: make([]LogDir, 0, len(b.DiskState)),
+		MinPct:  math.Inf(1),
+	}
+
+	var live int
+	for path, s := range b.DiskState {
+		d := LogDir{
+			Broker:   b.Broker,
+			Path:     path,
+			UsageMB:  s.DiskMB.Usage,
+			UsagePct: s.DiskPct.Usage,
+			Replicas: s.NumReplicas,
+			Leaders:  s.NumLeaderReplicas,
+			Dead:     s.DiskMB.Dead || s.DiskPct.Dead,
+		}
+		if !d.Dead {
+			d.NearlyFull = d.UsagePct >= config.NearlyFullPct
+			live++
+			disks.MeanPct += d.UsagePct
+			disks.MinPct = math.Min(disks.MinPct, d.UsagePct)
+			disks.MaxPct = math.Max(disks.MaxPct, d.UsagePct)
+		}
+		disks.LogDirs = append(disks.LogDirs, d)
+	}
+	sort.Slice(disks.LogDirs, func(i, j int) bool { return disks.LogDirs[i].Path < disks.LogDirs[j].Path })
+
+	if live == 0 {
+		disks.MinPct = 0
+		return disks
+	}
+	disks.MeanPct /= float64(live)
+	disks.SpreadPct = disks.MaxPct - disks.MinPct
+	disks.Imbalanced = live > 1 && disks.SpreadPct > config.MaxSpreadPct
+	disks.NoHeadroom = disks.MeanPct >= config.NearlyFullPct
+	return disks
+}
+
+// recommend returns the evacuation of the dead log dirs if there are any as intra-broker rebalances cannot be
+// run with offline replicas. Otherwise, an intra-broker rebalance is recommended if any broker is imbalanced
+// or has nearly full log dirs which can be relieved by its other log dirs.
+func (r *Report) recommend() Recommendation {
+	if len(r.DeadLogDirs) > 0 {
+		dirs := make(types.BrokerIDAndLogDirs)
+		for _, d := range r.DeadLogDirs {
+			dirs[d.Broker] = append(dirs[d.Broker], d.Path)
+		}
+
+		req := api.FixOfflineReplicasRequestWithDefaults()
+		req.DryRun = true
+		req.Reason = fmt.Sprintf("evacuate %d dead log dirs", len(r.DeadLogDirs))
+		return Recommendation{
+			Action:             ActionEvacuateLogDirs,
+			Reason:             fmt.Sprintf("%d log dirs are dead, their replicas need to be moved", len(r.DeadLogDirs)),
+			LogDirs:            dirs,
+			FixOfflineReplicas: req,
+		}
+	}
+
+	var imbalanced, relievable, noHeadroom int
+	for _, b := range r.Brokers {
+		hasNearlyFull := false
+		for _, d := range b.LogDirs {
+			hasNearlyFull = hasNearlyFull || d.NearlyFull
+		}
+		switch {
+		case b.Imbalanced:
+			imbalanced++
+		case hasNearlyFull && !b.NoHeadroom:
+			relievable++
+		}
+		if hasNearlyFull && b.NoHeadroom {
+			noHeadroom++
+		}
+	}
+
+	if imbalanced+relievable == 0 {
+		reason := "log dirs are balanced"
+		if noHeadroom > 0 {
+			reason = fmt.Sprintf("%d brokers are nearly full on every log dir, "+
+				"which needs an inter-broker rebalance or more capacity", noHeadroom)
+		}
+		return Recommendation{Action: ActionNone, Reason: reason}
+	}
+
+	req := api.RebalanceRequestWithDefaults()
+	req.DryRun = true
+	req.RebalanceDisk = true
+	req.Goals = []types.Goal{types.IntraBrokerDiskCapacityGoal, types.IntraBrokerDiskUsageDistributionGoal}
+	req.Reason = fmt.Sprintf("balance log dirs of %d brokers", imbalanced+relievable)
+	return Recommendation{
+		Action: ActionRebalanceDisk,
+		Reason: fmt.Sprintf("%d brokers have imbalanced log dirs and %d brokers have nearly full log dirs "+
+			"which can be relieved by their other log dirs", imbalanced, relievable),
+		Rebalance: req,
+	}
+}
+
+// Simulation compares the disk balance before and after a rebalance.
+type Simulation struct {
+	Before *Report `json:"before"`
+	After  *Report `json:"after"`
+	// Intra-broker data to move and the number of intra-broker replica movements of the rebalance.
+	DataToMoveMB     int64 `json:"dataToMoveMB"`
+	ReplicaMovements int32 `json:"replicaMovements"`
+	// ResolvedBrokers are the brokers which are imbalanced before but not after the rebalance.
+	ResolvedBrokers []int32 `json:"resolvedBrokers"`
+	// RemainingBrokers are the brokers which are imbalanced after the rebalance.
+	RemainingBrokers []int32 `json:"remainingBrokers"`
+}
+
+// Simulate returns the disk balance after executing the dry-run result of a rebalance with RebalanceDisk using
+// the configuration of the report. It returns an error if the load after the optimization does not contain disk
+// information.
+func (r *Report) Simulate(result *types.OptimizationResult) (*Simulation, error) {
+	if result == nil {
+		return nil, errors.New("optimization result is missing")
+	}
+	after, err := Analyze(&result.LoadAfterOptimization, r.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze load after optimization: %w", err)
+	}
+
+	sim := &Simulation{
+		Before:           r,
+		After:            after,
+		DataToMoveMB:     result.Summary.IntraBrokerDataToMoveMB,
+		ReplicaMovements: result.Summary.NumIntraBrokerReplicaMovements,
+		ResolvedBrokers:  make([]int32, 0),
+		RemainingBrokers: after.ImbalancedBrokers(),
+	}
+	remaining := make(map[int32]bool, len(sim.RemainingBrokers))
+	for _, b := range sim.RemainingBrokers {
+		remaining[b] = true
+	}
+	for _, b := range r.ImbalancedBrokers() {
+		if !remaining[b] {
+			sim.ResolvedBrokers = append(sim.ResolvedBrokers, b)
+		}
+	}
+	return sim, nil
+}
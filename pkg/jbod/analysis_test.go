@@ -0,0 +1,128 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jbod
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func disk(pct float64, dead bool) types.DiskStats {
+	return types.DiskStats{
+		DiskMB:      types.DiskUsageStat{Usage: pct * 10, Dead: dead},
+		DiskPct:     types.DiskUsageStat{Usage: pct, Dead: dead},
+		NumReplicas: int32(pct),
+	}
+}
+
+func load(disks map[int32]map[string]types.DiskStats) *types.BrokerStats {
+	stats := &types.BrokerStats{}
+	for id, d := range disks {
+		stats.Brokers = append(stats.Brokers, types.BrokerLoadStats{Broker: id, DiskState: d})
+	}
+	return stats
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Run("Missing disk information", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := Analyze(&types.BrokerStats{Brokers: []types.BrokerLoadStats{{Broker: 0}}}, Config{})
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("Balanced disks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report, err := Analyze(load(map[int32]map[string]types.DiskStats{
+			0: {"/d1": disk(40, false), "/d2": disk(45, false)},
+			1: {"/d1": disk(30, false)},
+		}), Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Brokers).To(HaveLen(2))
+		g.Expect(report.Brokers[0].SpreadPct).To(BeNumerically("~", 5))
+		g.Expect(report.Brokers[0].MeanPct).To(BeNumerically("~", 42.5))
+		g.Expect(report.ImbalancedBrokers()).To(BeEmpty())
+		g.Expect(report.Recommendation.Action).To(Equal(ActionNone))
+	})
+
+	t.Run("Imbalanced and nearly full disks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report, err := Analyze(load(map[int32]map[string]types.DiskStats{
+			0: {"/d1": disk(90, false), "/d2": disk(30, false)},
+			1: {"/d1": disk(90, false), "/d2": disk(95, false)},
+		}), Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.ImbalancedBrokers()).To(Equal([]int32{0}))
+		g.Expect(report.NearlyFullLogDirs).To(HaveLen(3))
+		g.Expect(report.Brokers[1].NoHeadroom).To(BeTrue())
+
+		rec := report.Recommendation
+		g.Expect(rec.Action).To(Equal(ActionRebalanceDisk))
+		g.Expect(rec.Rebalance).NotTo(BeNil())
+		g.Expect(rec.Rebalance.RebalanceDisk).To(BeTrue())
+		g.Expect(rec.Rebalance.DryRun).To(BeTrue())
+		g.Expect(rec.Rebalance.Goals).To(ConsistOf(types.IntraBrokerDiskCapacityGoal,
+			types.IntraBrokerDiskUsageDistributionGoal))
+	})
+
+	t.Run("Dead disks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		report, err := Analyze(load(map[int32]map[string]types.DiskStats{
+			0: {"/d1": disk(90, false), "/d2": disk(0, true)},
+		}), Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.DeadLogDirs).To(HaveLen(1))
+		g.Expect(report.Brokers[0].Imbalanced).To(BeFalse())
+
+		rec := report.Recommendation
+		g.Expect(rec.Action).To(Equal(ActionEvacuateLogDirs))
+		g.Expect(rec.LogDirs).To(Equal(types.BrokerIDAndLogDirs{0: {"/d2"}}))
+		g.Expect(rec.FixOfflineReplicas).NotTo(BeNil())
+	})
+}
+
+func TestSimulate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	report, err := Analyze(load(map[int32]map[string]types.DiskStats{
+		0: {"/d1": disk(90, false), "/d2": disk(30, false)},
+		1: {"/d1": disk(80, false), "/d2": disk(20, false)},
+	}), Config{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	after := load(map[int32]map[string]types.DiskStats{
+		0: {"/d1": disk(62, false), "/d2": disk(58, false)},
+		1: {"/d1": disk(75, false), "/d2": disk(25, false)},
+	})
+	sim, err := report.Simulate(&types.OptimizationResult{
+		LoadAfterOptimization: *after,
+		Summary:               types.OptimizerResult{IntraBrokerDataToMoveMB: 280, NumIntraBrokerReplicaMovements: 4},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sim.DataToMoveMB).To(Equal(int64(280)))
+	g.Expect(sim.ResolvedBrokers).To(Equal([]int32{0}))
+	g.Expect(sim.RemainingBrokers).To(Equal([]int32{1}))
+
+	_, err = report.Simulate(&types.OptimizationResult{})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/anomaly"
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const DefaultPollInterval = 30 * time.Second
+
+// Client is implemented by clients which are able to retrieve the state and the user tasks of Cruise Control.
+type Client interface {
+	State(ctx context.Context, r *api.StateRequest) (*api.StateResponse, error)
+	UserTasks(ctx context.Context, r *api.UserTasksRequest) (*api.UserTasksResponse, error)
+}
+
+// MonitorConfig contains the configuration parameters for the Monitor.
+type MonitorConfig struct {
+	// Interval between polling Cruise Control. DefaultPollInterval is used if not set.
+	Interval time.Duration
+	// Labels added to every notification, e.g. the name of the Kafka cluster.
+	Labels map[string]string
+	// Whether to send notifications about new anomalies.
+	Anomalies bool
+}
+
+// Monitor polls Cruise Control and sends notifications about executions, failed user tasks,
+// dead partition movements and anomalies.
+type Monitor struct {
+	client   Client
+	notifier *Notifier
+	config   MonitorConfig
+
+	executor    *types.ExecutorState
+	failedTasks map[string]bool
+	deadTasks   map[int64]bool
+	// Whether the executor state and the user tasks were retrieved already. Notifications are only sent about
+	// the changes since then.
+	executorInitialized bool
+	tasksInitialized    bool
+}
+
+// NewMonitor returns a new Monitor sending notifications using the provided Notifier.
+func NewMonitor(client Client, notifier *Notifier, config MonitorConfig) *Monitor {
+	if config.Interval <= 0 {
+		config.Interval = DefaultPollInterval
+	}
+	return &Monitor{
+		client:      client,
+		notifier:    notifier,
+		config:      config,
+		failedTasks: make(map[string]bool),
+		deadTasks:   make(map[int64]bool),
+	}
+}
+
+// Run polls Cruise Control until the context is cancelled.
+func (m *Monitor) Run(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	if m.config.Anomalies {
+		watcher := anomaly.NewWatcher(m.client, anomaly.WatcherConfig{Interval: m.config.Interval})
+		go func() {
+			defer close(done)
+			for event := range watcher.Events() {
+				if n, ok := m.anomalyNotification(event); ok {
+					if err := m.notifier.Notify(ctx, n); err != nil {
+						log.Error(err, "failed to send anomaly notification")
+					}
+				}
+			}
+		}()
+		go func() {
+			_ = watcher.Run(ctx)
+		}()
+	} else {
+		close(done)
+	}
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Error(err, "failed to poll Cruise Control")
+		}
+
+		select {
+		case <-ctx.Done():
+			<-done
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks the executor state and the user tasks once and sends notifications about the changes. Failing to
+// retrieve one of them does not prevent sending the notifications about the other.
+func (m *Monitor) Poll(ctx context.Context) error {
+	var errs []error
+	notifications := make([]Notification, 0)
+
+	executorNotifications, err := m.executorNotifications(ctx)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case m.executorInitialized:
+		notifications = append(notifications, executorNotifications...)
+	default:
+		m.executorInitialized = true
+	}
+
+	taskNotifications, err := m.userTaskNotifications(ctx)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case m.tasksInitialized:
+		notifications = append(notifications, taskNotifications...)
+	default:
+		m.tasksInitialized = true
+	}
+
+	for _, n := range notifications {
+		if err = m.notifier.Notify(ctx, n); err != nil {
+			m.forget(n)
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// forget removes the notification from the observed user tasks and partition movements, so it is sent again
+// at the next poll if the delivery failed.
+func (m *Monitor) forget(n Notification) {
+	switch n.Kind {
+	case KindUserTaskFailed:
+		delete(m.failedTasks, n.Key)
+	case KindDeadPartitionMovement:
+		if id, err := strconv.ParseInt(n.Key, 10, 64); err == nil {
+			delete(m.deadTasks, id)
+		}
+	}
+}
+
+func (m *Monitor) executorNotifications(ctx context.Context) ([]Notification, error) {
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateExecutor}
+	req.Verbose = true
+
+	resp, err := m.client.State(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executor state: %w", err)
+	}
+	if resp.Result == nil {
+		return nil, errors.New("executor state is not available")
+	}
+
+	current := resp.Result.ExecutorState
+	previous := m.executor
+	m.executor = &current
+
+	notifications := make([]Notification, 0)
+	if previous != nil {
+		wasRunning := previous.State != types.ExecutorStateTypeNoTaskInProgress
+		isRunning := current.State != types.ExecutorStateTypeNoTaskInProgress
+		switch {
+		case !wasRunning && isRunning:
+			notifications = append(notifications, m.newNotification(KindExecutionStarted, types.SeverityInfo,
+				executionKey(current), current))
+		case wasRunning && !isRunning:
+			notifications = append(notifications, m.newNotification(KindExecutionFinished, types.SeverityInfo,
+				executionKey(*previous), *previous))
+		}
+	}
+
+	deadTasks := make(map[int64]bool)
+	for _, task := range append(current.DeadPartitionMovement, current.DeadIntraBrokerPartitionMovement...) {
+		deadTasks[task.ExecutionID] = true
+		if m.deadTasks[task.ExecutionID] {
+			continue
+		}
+		notifications = append(notifications, m.newNotification(KindDeadPartitionMovement, types.SeverityCritical,
+			strconv.FormatInt(task.ExecutionID, 10), task))
+	}
+	m.deadTasks = deadTasks
+
+	return notifications, nil
+}
+
+// executionKey returns the ID of the user or self-healing task which triggered the execution.
+func executionKey(s types.ExecutorState) string {
+	if s.TriggeredUserTaskID != "" {
+		return s.TriggeredUserTaskID
+	}
+	return s.TriggeredSelfHealingTaskID
+}
+
+func (m *Monitor) userTaskNotifications(ctx context.Context) ([]Notification, error) {
+	req := api.UserTasksRequestWithDefaults()
+	req.Types = []types.UserTaskStatus{types.UserTaskStatusCompletedWithError}
+
+	resp, err := m.client.UserTasks(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+	if resp.Result == nil {
+		return nil, errors.New("user tasks are not available")
+	}
+
+	// Tasks which are no longer reported by Cruise Control are forgotten to keep memory usage bounded.
+	failedTasks := make(map[string]bool)
+	notifications := make([]Notification, 0)
+	for _, task := range resp.Result.UserTasks {
+		if task.Status != types.UserTaskStatusCompletedWithError {
+			continue
+		}
+		failedTasks[task.UserTaskID] = true
+		if m.failedTasks[task.UserTaskID] {
+			continue
+		}
+		notifications = append(notifications, m.newNotification(KindUserTaskFailed, types.SeverityWarning,
+			task.UserTaskID, task))
+	}
+	m.failedTasks = failedTasks
+	return notifications, nil
+}
+
+func (m *Monitor) anomalyNotification(event anomaly.Event) (Notification, bool) {
+	if event.Type != anomaly.EventTypeAnomalyDetected || event.Anomaly == nil {
+		return Notification{}, false
+	}
+
+	severity := types.SeverityWarning
+	if event.Anomaly.Type == types.AnomalyTypeBrokerFailure || event.Anomaly.Type == types.AnomalyTypeDiskFailure {
+		severity = types.SeverityCritical
+	}
+
+	n := m.newNotification(KindAnomalyDetected, severity, event.Anomaly.AnomalyID.String(), *event.Anomaly)
+	n.Labels["anomaly_type"] = event.Anomaly.Type.String()
+	return n, true
+}
+
+func (m *Monitor) newNotification(kind Kind, severity types.Severity, key string, data interface{}) Notification {
+	labels := make(map[string]string, len(m.config.Labels))
+	for k, v := range m.config.Labels {
+		labels[k] = v
+	}
+	return Notification{
+		Kind:     kind,
+		Severity: severity,
+		Time:     time.Now(),
+		Key:      key,
+		Labels:   labels,
+		Data:     data,
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	KindUndefined Kind = iota
+	KindExecutionStarted
+	KindExecutionFinished
+	KindUserTaskFailed
+	KindDeadPartitionMovement
+	KindAnomalyDetected
+)
+
+// Kind is an enum for the type of events notifications are sent about.
+type Kind int8
+
+func (k Kind) String() string {
+	switch k {
+	case KindExecutionStarted:
+		return "EXECUTION_STARTED"
+	case KindExecutionFinished:
+		return "EXECUTION_FINISHED"
+	case KindUserTaskFailed:
+		return "USER_TASK_FAILED"
+	case KindDeadPartitionMovement:
+		return "DEAD_PARTITION_MOVEMENT"
+	case KindAnomalyDetected:
+		return "ANOMALY_DETECTED"
+	case KindUndefined:
+		fallthrough
+	default:
+		return types.Undefined
+	}
+}
+
+// Notification is a single message delivered to the configured sinks.
+type Notification struct {
+	Kind     Kind
+	Severity types.Severity
+	Time     time.Time
+	// Key identifies the event the notification is about and used for deduplication.
+	Key string
+	// Message is rendered from the template configured for the Kind of the notification.
+	Message string
+	// Labels are attached to the notification, e.g. as Alertmanager labels.
+	Labels map[string]string
+	// Data holds the object the notification was created from and it is available in message templates.
+	Data interface{}
+}
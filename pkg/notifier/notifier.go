@@ -0,0 +1,218 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	DefaultDedupWindow = time.Hour
+	DefaultRateLimit   = 10
+	DefaultRateWindow  = time.Minute
+)
+
+// DefaultTemplates returns the message templates used for kinds without custom template.
+func DefaultTemplates() map[Kind]string {
+	return map[Kind]string{
+		KindExecutionStarted: `Cruise Control started executing user task {{ .Data.TriggeredUserTaskID }}` +
+			`{{ with .Data.TriggeredTaskReason }} (reason: {{ . }}){{ end }}`,
+		KindExecutionFinished: `Cruise Control finished executing user task {{ .Data.TriggeredUserTaskID }}`,
+		KindUserTaskFailed: `Cruise Control user task {{ .Data.UserTaskID }} completed with error ` +
+			`(request: {{ .Data.RequestURL }})`,
+		KindDeadPartitionMovement: `Partition movement of {{ .Data.Proposal.TopicPartition.Topic }}-` +
+			`{{ .Data.Proposal.TopicPartition.Partition }} is dead ` +
+			`(replicas: {{ .Data.Proposal.OldReplicas }} -> {{ .Data.Proposal.NewReplicas }})`,
+		KindAnomalyDetected: `Cruise Control detected {{ .Data.Type }} anomaly {{ .Data.AnomalyID }} ` +
+			`with status {{ .Data.Status }}{{ with .Data.Description }}: {{ . }}{{ end }}`,
+	}
+}
+
+// Sink delivers notifications to an external system.
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Config contains the configuration parameters for the Notifier.
+type Config struct {
+	// Sinks the notifications are delivered to.
+	Sinks []Sink
+	// Templates overrides the default message templates per Kind.
+	Templates map[Kind]string
+	// Notifications with the same Kind and Key are sent only once within the DedupWindow.
+	// DefaultDedupWindow is used if not set.
+	DedupWindow time.Duration
+	// At most RateLimit notifications are sent within RateWindow, notifications over the limit are dropped.
+	// DefaultRateLimit and DefaultRateWindow are used if not set.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// Notifier renders, deduplicates and rate limits notifications before delivering them to sinks.
+type Notifier struct {
+	sinks       []Sink
+	templates   map[Kind]*template.Template
+	dedupWindow time.Duration
+	rateLimit   int
+	rateWindow  time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+	sent []time.Time
+}
+
+// NewNotifier returns a new Notifier with the provided configuration. It returns an error if any of the
+// message templates is invalid.
+func NewNotifier(config Config) (*Notifier, error) {
+	n := &Notifier{
+		sinks:       config.Sinks,
+		templates:   make(map[Kind]*template.Template),
+		dedupWindow: config.DedupWindow,
+		rateLimit:   config.RateLimit,
+		rateWindow:  config.RateWindow,
+		seen:        make(map[string]time.Time),
+	}
+	if n.dedupWindow <= 0 {
+		n.dedupWindow = DefaultDedupWindow
+	}
+	if n.rateLimit <= 0 {
+		n.rateLimit = DefaultRateLimit
+	}
+	if n.rateWindow <= 0 {
+		n.rateWindow = DefaultRateWindow
+	}
+
+	templates := DefaultTemplates()
+	for k, t := range config.Templates {
+		templates[k] = t
+	}
+	for k, t := range templates {
+		tmpl, err := template.New(k.String()).Parse(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message template for %s: %w", k, err)
+		}
+		n.templates[k] = tmpl
+	}
+
+	return n, nil
+}
+
+// Notify delivers the notification to all sinks unless it is a duplicate or the rate limit is reached.
+// Delivery continues with the remaining sinks if a sink fails and the errors of the sinks are returned joined.
+// The notification is only considered sent for deduplication if at least one sink delivered it.
+func (n *Notifier) Notify(ctx context.Context, notification Notification) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+
+	if !n.admit(notification) {
+		log.V(1).Info("notification dropped", "kind", notification.Kind, "key", notification.Key)
+		return nil
+	}
+
+	if notification.Message == "" {
+		msg, err := n.render(notification)
+		if err != nil {
+			return err
+		}
+		notification.Message = msg
+	}
+
+	var errs []error
+	delivered := false
+	for _, sink := range n.sinks {
+		if err := sink.Send(ctx, notification); err != nil {
+			log.Error(err, "failed to send notification", "kind", notification.Kind, "key", notification.Key)
+			errs = append(errs, err)
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		n.markSent(notification)
+	}
+	return errors.Join(errs...)
+}
+
+// admit returns true if the notification is not a duplicate of a notification sent within the dedup window
+// and the rate limit is not reached yet.
+func (n *Notifier) admit(notification Notification) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := notification.Time
+
+	for k, t := range n.seen {
+		if now.Sub(t) >= n.dedupWindow {
+			delete(n.seen, k)
+		}
+	}
+	if _, ok := n.seen[dedupKey(notification)]; ok && notification.Key != "" {
+		return false
+	}
+
+	sent := n.sent[:0]
+	for _, t := range n.sent {
+		if now.Sub(t) < n.rateWindow {
+			sent = append(sent, t)
+		}
+	}
+	n.sent = sent
+	if len(n.sent) >= n.rateLimit {
+		return false
+	}
+
+	n.sent = append(n.sent, now)
+	return true
+}
+
+// markSent records the notification for deduplication.
+func (n *Notifier) markSent(notification Notification) {
+	if notification.Key == "" {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seen[dedupKey(notification)] = notification.Time
+}
+
+func dedupKey(notification Notification) string {
+	return fmt.Sprintf("%s/%s", notification.Kind, notification.Key)
+}
+
+func (n *Notifier) render(notification Notification) (string, error) {
+	tmpl, ok := n.templates[notification.Kind]
+	if !ok {
+		return notification.Kind.String(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return "", fmt.Errorf("failed to render message for %s notification: %w", notification.Kind, err)
+	}
+	return buf.String(), nil
+}
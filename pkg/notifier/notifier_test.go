@@ -0,0 +1,252 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	paths    []string
+	payloads [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, req.URL.Path)
+	r.payloads = append(r.payloads, body)
+	w.WriteHeader(http.StatusOK)
+}
+
+func userTaskFailed(id string) Notification {
+	return Notification{
+		Kind:     KindUserTaskFailed,
+		Severity: types.SeverityWarning,
+		Time:     time.Unix(1700000000, 0),
+		Key:      id,
+		Labels:   map[string]string{"cluster": "kafka"},
+		Data: types.UserTaskInfo{
+			UserTaskID: id,
+			RequestURL: "POST /kafkacruisecontrol/rebalance",
+		},
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("Send to sinks", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		rec := &recorder{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		n, err := NewNotifier(Config{
+			Sinks: []Sink{
+				WebhookSink{URL: server.URL + "/webhook"},
+				SlackSink{URL: server.URL + "/slack"},
+				AlertmanagerSink{URL: server.URL + "/"},
+			},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(n.Notify(context.Background(), userTaskFailed("task-1"))).To(Succeed())
+
+		g.Expect(rec.paths).To(Equal([]string{"/webhook", "/slack", "/api/v2/alerts"}))
+
+		webhook := map[string]interface{}{}
+		g.Expect(json.Unmarshal(rec.payloads[0], &webhook)).To(Succeed())
+		g.Expect(webhook["kind"]).To(Equal("USER_TASK_FAILED"))
+		g.Expect(webhook["message"]).To(Equal(
+			"Cruise Control user task task-1 completed with error (request: POST /kafkacruisecontrol/rebalance)"))
+
+		slack := map[string]interface{}{}
+		g.Expect(json.Unmarshal(rec.payloads[1], &slack)).To(Succeed())
+		g.Expect(slack["text"]).To(HavePrefix("[warning] Cruise Control user task task-1"))
+
+		var alerts []map[string]interface{}
+		g.Expect(json.Unmarshal(rec.payloads[2], &alerts)).To(Succeed())
+		g.Expect(alerts).To(HaveLen(1))
+		g.Expect(alerts[0]["labels"]).To(HaveKeyWithValue("alertname", "CruiseControlUserTaskFailed"))
+		g.Expect(alerts[0]["labels"]).To(HaveKeyWithValue("cluster", "kafka"))
+		g.Expect(alerts[0]["labels"]).To(HaveKeyWithValue("key", "task-1"))
+	})
+
+	t.Run("Custom template", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		rec := &recorder{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		n, err := NewNotifier(Config{
+			Sinks:     []Sink{SlackSink{URL: server.URL}},
+			Templates: map[Kind]string{KindUserTaskFailed: `task {{ .Key }} failed on {{ index .Labels "cluster" }}`},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(n.Notify(context.Background(), userTaskFailed("task-1"))).To(Succeed())
+
+		slack := map[string]interface{}{}
+		g.Expect(json.Unmarshal(rec.payloads[0], &slack)).To(Succeed())
+		g.Expect(slack["text"]).To(Equal("[warning] task task-1 failed on kafka"))
+	})
+
+	t.Run("Deduplicate and rate limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		rec := &recorder{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		n, err := NewNotifier(Config{
+			Sinks:      []Sink{WebhookSink{URL: server.URL}},
+			RateLimit:  2,
+			RateWindow: time.Minute,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		ctx := context.Background()
+		g.Expect(n.Notify(ctx, userTaskFailed("task-1"))).To(Succeed())
+		g.Expect(n.Notify(ctx, userTaskFailed("task-1"))).To(Succeed())
+		g.Expect(rec.payloads).To(HaveLen(1))
+
+		g.Expect(n.Notify(ctx, userTaskFailed("task-2"))).To(Succeed())
+		g.Expect(n.Notify(ctx, userTaskFailed("task-3"))).To(Succeed())
+		g.Expect(rec.payloads).To(HaveLen(2))
+	})
+	t.Run("Failed delivery is not deduplicated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		failing := true
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if failing {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		n, err := NewNotifier(Config{Sinks: []Sink{WebhookSink{URL: server.URL}, SlackSink{URL: server.URL}}})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		ctx := context.Background()
+		err = n.Notify(ctx, userTaskFailed("task-1"))
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("500"))
+
+		failing = false
+		g.Expect(n.Notify(ctx, userTaskFailed("task-1"))).To(Succeed())
+		g.Expect(n.seen).To(HaveKey("USER_TASK_FAILED/task-1"))
+	})
+}
+
+type fakeClient struct {
+	executor types.ExecutorStateType
+	tasks    []types.UserTaskInfo
+	tasksErr error
+}
+
+func (f *fakeClient) State(_ context.Context, _ *api.StateRequest) (*api.StateResponse, error) {
+	state := f.executor
+	if state == types.ExecutorStateTypeUndefined {
+		state = types.ExecutorStateTypeNoTaskInProgress
+	}
+	return &api.StateResponse{Result: &types.StateResult{
+		ExecutorState: types.ExecutorState{State: state, TriggeredUserTaskID: "task-3"},
+	}}, nil
+}
+
+func (f *fakeClient) UserTasks(_ context.Context, _ *api.UserTasksRequest) (*api.UserTasksResponse, error) {
+	if f.tasksErr != nil {
+		return nil, f.tasksErr
+	}
+	return &api.UserTasksResponse{Result: &types.UserTaskState{UserTasks: f.tasks}}, nil
+}
+
+func TestMonitor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n, err := NewNotifier(Config{Sinks: []Sink{WebhookSink{URL: server.URL}}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	failed := func(id string) types.UserTaskInfo {
+		return types.UserTaskInfo{UserTaskID: id, Status: types.UserTaskStatusCompletedWithError}
+	}
+	client := &fakeClient{tasks: []types.UserTaskInfo{failed("task-1")}}
+	m := NewMonitor(client, n, MonitorConfig{})
+
+	ctx := context.Background()
+	g.Expect(m.Poll(ctx)).To(Succeed())
+	g.Expect(rec.payloads).To(BeEmpty())
+
+	client.tasks = append(client.tasks, failed("task-2"))
+	g.Expect(m.Poll(ctx)).To(Succeed())
+	g.Expect(rec.payloads).To(HaveLen(1))
+	g.Expect(m.failedTasks).To(HaveLen(2))
+
+	// Tasks which expired from the user task list are forgotten.
+	client.tasks = []types.UserTaskInfo{failed("task-2")}
+	g.Expect(m.Poll(ctx)).To(Succeed())
+	g.Expect(rec.payloads).To(HaveLen(1))
+	g.Expect(m.failedTasks).To(Equal(map[string]bool{"task-2": true}))
+}
+
+func TestMonitorFailedSource(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n, err := NewNotifier(Config{Sinks: []Sink{WebhookSink{URL: server.URL}}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	client := &fakeClient{}
+	m := NewMonitor(client, n, MonitorConfig{})
+
+	ctx := context.Background()
+	g.Expect(m.Poll(ctx)).To(Succeed())
+
+	// The execution is reported even though the user tasks are not available.
+	client.executor = types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress
+	client.tasksErr = errors.New("boom")
+	g.Expect(m.Poll(ctx)).To(MatchError(ContainSubstring("boom")))
+	g.Expect(rec.payloads).To(HaveLen(1))
+
+	var payload map[string]interface{}
+	g.Expect(json.Unmarshal(rec.payloads[0], &payload)).To(Succeed())
+	g.Expect(payload).To(HaveKeyWithValue("kind", "EXECUTION_STARTED"))
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("unexpected HTTP status code %d returned by %s", resp.StatusCode, url)
+	}
+	return nil
+}
+
+// WebhookSink posts notifications as generic JSON documents.
+type WebhookSink struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+type webhookPayload struct {
+	Kind     string            `json:"kind"`
+	Severity string            `json:"severity"`
+	Time     time.Time         `json:"time"`
+	Key      string            `json:"key,omitempty"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Data     interface{}       `json:"data,omitempty"`
+}
+
+func (s WebhookSink) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.HTTPClient, s.URL, webhookPayload{
+		Kind:     n.Kind.String(),
+		Severity: n.Severity.String(),
+		Time:     n.Time,
+		Key:      n.Key,
+		Message:  n.Message,
+		Labels:   n.Labels,
+		Data:     n.Data,
+	})
+}
+
+// SlackSink posts notifications using the Slack incoming webhook payload format.
+type SlackSink struct {
+	URL        string
+	HTTPClient *http.Client
+	// Optional channel overriding the default channel of the webhook.
+	Channel string
+}
+
+type slackPayload struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+func (s SlackSink) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.HTTPClient, s.URL, slackPayload{
+		Channel: s.Channel,
+		Text:    fmt.Sprintf("[%s] %s", n.Severity, n.Message),
+	})
+}
+
+const alertmanagerAlertsPath = "/api/v2/alerts"
+
+// AlertmanagerSink posts notifications as alerts to the Alertmanager API.
+type AlertmanagerSink struct {
+	// URL of Alertmanager, e.g. http://alertmanager:9093
+	URL        string
+	HTTPClient *http.Client
+	// Labels added to every alert.
+	Labels map[string]string
+	// Alerts are resolved by Alertmanager after this period. Alertmanager's resolve_timeout is used if not set.
+	ResolveAfter time.Duration
+}
+
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+func (s AlertmanagerSink) Send(ctx context.Context, n Notification) error {
+	// Alertmanager groups and deduplicates alerts by their labels, so the key keeps the alerts about different
+	// tasks and anomalies apart.
+	labels := map[string]string{
+		"alertname": "CruiseControl" + kindAlertName(n.Kind),
+		"severity":  n.Severity.String(),
+	}
+	if n.Key != "" {
+		labels["key"] = n.Key
+	}
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	for k, v := range n.Labels {
+		labels[k] = v
+	}
+
+	alert := alertmanagerAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary": n.Message,
+		},
+		StartsAt: n.Time,
+	}
+	if s.ResolveAfter > 0 {
+		endsAt := n.Time.Add(s.ResolveAfter)
+		alert.EndsAt = &endsAt
+	}
+
+	return postJSON(ctx, s.HTTPClient, strings.TrimSuffix(s.URL, "/")+alertmanagerAlertsPath, []alertmanagerAlert{alert})
+}
+
+func kindAlertName(k Kind) string {
+	switch k {
+	case KindExecutionStarted:
+		return "ExecutionStarted"
+	case KindExecutionFinished:
+		return "ExecutionFinished"
+	case KindUserTaskFailed:
+		return "UserTaskFailed"
+	case KindDeadPartitionMovement:
+		return "DeadPartitionMovement"
+	case KindAnomalyDetected:
+		return "AnomalyDetected"
+	case KindUndefined:
+		fallthrough
+	default:
+		return "Event"
+	}
+}
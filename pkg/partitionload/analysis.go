@@ -0,0 +1,288 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partitionload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultTopN               = 10
+	DefaultMaxToMeanLimit     = 2.0
+	DefaultGiniLimit          = 0.4
+	DefaultMinTopicPartitions = 3
+	DefaultPollInterval       = 5 * time.Second
+)
+
+// Client is implemented by clients which are able to retrieve the partition load from Cruise Control.
+type Client interface {
+	KafkaPartitionLoad(ctx context.Context, r *api.KafkaPartitionLoadRequest) (*api.KafkaPartitionLoadResponse, error)
+}
+
+// Config contains the configuration parameters of the partition load analysis.
+type Config struct {
+	// TopN is the number of hottest partitions reported per resource. DefaultTopN is used if not set.
+	TopN int
+	// MaxToMeanLimit is the max/mean ratio of the partition load of a topic above which the topic is flagged.
+	// DefaultMaxToMeanLimit is used if not set.
+	MaxToMeanLimit float64
+	// GiniLimit is the Gini coefficient of the partition load of a topic above which the topic is flagged.
+	// DefaultGiniLimit is used if not set.
+	GiniLimit float64
+	// MinTopicPartitions is the minimum number of partitions of a topic to be flagged.
+	// DefaultMinTopicPartitions is used if not set.
+	MinTopicPartitions int
+	// Request is used by Analyze to retrieve the partition load, e.g. to filter it by topic, partition or broker.
+	// api.KafkaPartitionLoadRequestWithDefaults is used if not set.
+	Request *api.KafkaPartitionLoadRequest
+	// PollInterval is the interval between checking whether Cruise Control finished computing the partition load.
+	// DefaultPollInterval is used if not set.
+	PollInterval time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.TopN <= 0 {
+		c.TopN = DefaultTopN
+	}
+	if c.MaxToMeanLimit <= 0 {
+		c.MaxToMeanLimit = DefaultMaxToMeanLimit
+	}
+	if c.GiniLimit <= 0 {
+		c.GiniLimit = DefaultGiniLimit
+	}
+	if c.MinTopicPartitions <= 0 {
+		c.MinTopicPartitions = DefaultMinTopicPartitions
+	}
+	if c.Request == nil {
+		c.Request = api.KafkaPartitionLoadRequestWithDefaults()
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	return c
+}
+
+// Hotspots are the partitions with the highest load of a resource.
+type Hotspots struct {
+	Resource   types.ResourceType    `json:"resource"`
+	Partitions []types.PartitionLoad `json:"partitions"`
+}
+
+// TopicSkew is the skew of the partition load of a topic.
+type TopicSkew struct {
+	Topic      string `json:"topic"`
+	Partitions int    `json:"partitions"`
+	Skew       []Skew `json:"skew"`
+	// KeyingProblem is true if the load is unevenly distributed among the partitions of the topic. It usually means
+	// that producers use skewed message keys which cannot be fixed by moving partitions between brokers.
+	KeyingProblem bool `json:"keyingProblem"`
+	// Reasons describes why the topic is flagged.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// BrokerLoad is the partition load attributed to a broker.
+type BrokerLoad struct {
+	Broker    int32 `json:"broker"`
+	Leaders   int   `json:"leaders"`
+	Followers int   `json:"followers"`
+	// LeaderLoad is the total load of the partitions the broker leads by resource in the order of Resources.
+	LeaderLoad []float64 `json:"leaderLoad"`
+	// FollowerLoad is the total disk and network inbound load of the follower replicas on the broker in the order of
+	// Resources. CPU and network outbound load is attributed to leaders only.
+	FollowerLoad []float64 `json:"followerLoad"`
+}
+
+// Analysis is the result of the partition load analysis.
+type Analysis struct {
+	Hotspots []Hotspots   `json:"hotspots"`
+	Topics   []TopicSkew  `json:"topics"`
+	Brokers  []BrokerLoad `json:"brokers"`
+	// LeaderSkew is the skew of the leader load among the brokers. High leader skew with even partition load
+	// within topics indicates a placement problem which can be fixed by rebalancing.
+	LeaderSkew []Skew `json:"leaderSkew"`
+}
+
+// FlaggedTopics returns the topics with keying problems.
+func (a *Analysis) FlaggedTopics() []TopicSkew {
+	flagged := make([]TopicSkew, 0)
+	for _, t := range a.Topics {
+		if t.KeyingProblem {
+			flagged = append(flagged, t)
+		}
+	}
+	return flagged
+}
+
+// Analyze retrieves the load of the partitions selected by the request of the configuration from Cruise Control
+// and analyzes it. If Cruise Control is still computing the partition load, Analyze waits for it to finish.
+func Analyze(ctx context.Context, c Client, config Config) (*Analysis, error) {
+	config = config.withDefaults()
+
+	// The requests are sent in the same session so Cruise Control returns the progress of the same user task
+	// until it finishes.
+	ctx = client.ContextWithSession(ctx)
+
+	ticker := time.NewTicker(config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := c.KafkaPartitionLoad(ctx, config.Request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partition load: %w", err)
+		}
+		if !resp.InProgress() {
+			return NewAnalysis(resp.Result, config)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("partition load is still being computed: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewAnalysis analyzes the partition load. It returns an error if the partition load is missing, e.g. because
+// Cruise Control returned an in progress response.
+func NewAnalysis(state *types.PartitionLoadState, config Config) (*Analysis, error) {
+	config = config.withDefaults()
+	if state == nil {
+		return nil, errors.New("partition load is not available")
+	}
+
+	brokers := brokerLoads(state.Records)
+	return &Analysis{
+		Hotspots:   hotspots(state.Records, config.TopN),
+		Topics:     topicSkews(state.Records, config),
+		Brokers:    brokers,
+		LeaderSkew: leaderSkews(brokers),
+	}, nil
+}
+
+func hotspots(records []types.PartitionLoad, n int) []Hotspots {
+	result := make([]Hotspots, 0, len(Resources))
+	for _, r := range Resources {
+		sorted := make([]types.PartitionLoad, len(records))
+		copy(sorted, records)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return Load(sorted[i], r) > Load(sorted[j], r)
+		})
+		if len(sorted) > n {
+			sorted = sorted[:n]
+		}
+		result = append(result, Hotspots{Resource: r, Partitions: sorted})
+	}
+	return result
+}
+
+func topicSkews(records []types.PartitionLoad, config Config) []TopicSkew {
+	byTopic := make(map[string][]types.PartitionLoad)
+	for _, p := range records {
+		byTopic[p.Topic] = append(byTopic[p.Topic], p)
+	}
+
+	topics := make([]TopicSkew, 0, len(byTopic))
+	for topic, partitions := range byTopic {
+		t := TopicSkew{
+			Topic:      topic,
+			Partitions: len(partitions),
+			Skew:       make([]Skew, 0, len(Resources)),
+		}
+		for _, r := range Resources {
+			values := make([]float64, 0, len(partitions))
+			for _, p := range partitions {
+				values = append(values, Load(p, r))
+			}
+			s := NewSkew(r, values)
+			t.Skew = append(t.Skew, s)
+
+			if t.Partitions < config.MinTopicPartitions || s.Total == 0 {
+				continue
+			}
+			if s.MaxToMean >= config.MaxToMeanLimit {
+				t.Reasons = append(t.Reasons, fmt.Sprintf("%s max/mean ratio is %.2f", r, s.MaxToMean))
+			}
+			if s.Gini >= config.GiniLimit {
+				t.Reasons = append(t.Reasons, fmt.Sprintf("%s Gini coefficient is %.2f", r, s.Gini))
+			}
+		}
+		t.KeyingProblem = len(t.Reasons) > 0
+		topics = append(topics, t)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Topic < topics[j].Topic })
+	return topics
+}
+
+func brokerLoads(records []types.PartitionLoad) []BrokerLoad {
+	byBroker := make(map[int32]*BrokerLoad)
+	get := func(id int32) *BrokerLoad {
+		b, ok := byBroker[id]
+		if !ok {
+			b = &BrokerLoad{
+				Broker:       id,
+				LeaderLoad:   make([]float64, len(Resources)),
+				FollowerLoad: make([]float64, len(Resources)),
+			}
+			byBroker[id] = b
+		}
+		return b
+	}
+
+	for _, p := range records {
+		leader := get(p.Leader)
+		leader.Leaders++
+		for i, r := range Resources {
+			leader.LeaderLoad[i] += Load(p, r)
+		}
+		for _, id := range p.Followers {
+			follower := get(id)
+			follower.Followers++
+			for i, r := range Resources {
+				if r == types.ResourceTypeDisk || r == types.ResourceTypeNetworkIn {
+					follower.FollowerLoad[i] += Load(p, r)
+				}
+			}
+		}
+	}
+
+	brokers := make([]BrokerLoad, 0, len(byBroker))
+	for _, b := range byBroker {
+		brokers = append(brokers, *b)
+	}
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i].Broker < brokers[j].Broker })
+	return brokers
+}
+
+func leaderSkews(brokers []BrokerLoad) []Skew {
+	skews := make([]Skew, 0, len(Resources))
+	for i, r := range Resources {
+		values := make([]float64, 0, len(brokers))
+		for _, b := range brokers {
+			values = append(values, b.LeaderLoad[i])
+		}
+		skews = append(skews, NewSkew(r, values))
+	}
+	return skews
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partitionload
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// fakeClient returns in progress responses for the first pending requests, then the partition load.
+type fakeClient struct {
+	load    *types.PartitionLoadState
+	pending int
+
+	requests []*api.KafkaPartitionLoadRequest
+}
+
+func (f *fakeClient) KafkaPartitionLoad(_ context.Context, r *api.KafkaPartitionLoadRequest) (*api.KafkaPartitionLoadResponse, error) { //nolint:lll
+	f.requests = append(f.requests, r)
+	if len(f.requests) <= f.pending {
+		return &api.KafkaPartitionLoadResponse{GenericResponse: types.GenericResponse{Progress: &types.ProgressResult{}}}, nil
+	}
+	return &api.KafkaPartitionLoadResponse{Result: f.load}, nil
+}
+
+func TestGini(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(Gini(nil)).To(BeZero())
+	g.Expect(Gini([]float64{1, 1, 1, 1})).To(BeNumerically("~", 0))
+	g.Expect(Gini([]float64{0, 0, 0, 4})).To(BeNumerically("~", 0.75))
+}
+
+func TestNewAnalysis(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	state := &types.PartitionLoadState{
+		Records: []types.PartitionLoad{
+			{Topic: "even", Partition: 0, Leader: 0, Followers: []int32{1}, NetworkIn: 10, Disk: 100},
+			{Topic: "even", Partition: 1, Leader: 1, Followers: []int32{0}, NetworkIn: 10, Disk: 100},
+			{Topic: "even", Partition: 2, Leader: 0, Followers: []int32{1}, NetworkIn: 10, Disk: 100},
+			{Topic: "keyed", Partition: 0, Leader: 1, Followers: []int32{0}, NetworkIn: 90, Disk: 900},
+			{Topic: "keyed", Partition: 1, Leader: 0, Followers: []int32{1}, NetworkIn: 1, Disk: 10},
+			{Topic: "keyed", Partition: 2, Leader: 1, Followers: []int32{0}, NetworkIn: 1, Disk: 10},
+		},
+	}
+
+	a, err := NewAnalysis(state, Config{TopN: 2})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(a.Hotspots).To(HaveLen(len(Resources)))
+	g.Expect(a.Hotspots[1].Resource).To(Equal(types.ResourceTypeDisk))
+	g.Expect(a.Hotspots[1].Partitions).To(HaveLen(2))
+	g.Expect(a.Hotspots[1].Partitions[0].Topic).To(Equal("keyed"))
+
+	flagged := a.FlaggedTopics()
+	g.Expect(flagged).To(HaveLen(1))
+	g.Expect(flagged[0].Topic).To(Equal("keyed"))
+
+	g.Expect(a.Brokers).To(HaveLen(2))
+	g.Expect(a.Brokers[1].Leaders).To(Equal(3))
+	g.Expect(a.Brokers[1].LeaderLoad[1]).To(BeNumerically("~", 1010))
+	g.Expect(a.Brokers[1].FollowerLoad[1]).To(BeNumerically("~", 210))
+}
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Waits for in progress responses", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		req := api.KafkaPartitionLoadRequestWithDefaults()
+		req.Topic = "orders"
+		client := &fakeClient{
+			load:    &types.PartitionLoadState{Records: []types.PartitionLoad{{Topic: "orders", Leader: 1, CPU: 10}}},
+			pending: 2,
+		}
+
+		analysis, err := Analyze(ctx, client, Config{Request: req, PollInterval: time.Millisecond})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(analysis.Brokers).To(HaveLen(1))
+		g.Expect(client.requests).To(HaveLen(3))
+		g.Expect(client.requests[0].Topic).To(Equal("orders"))
+	})
+
+	t.Run("Gives up when the context is done", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := Analyze(ctx, &fakeClient{pending: 1000}, Config{PollInterval: time.Millisecond})
+		g.Expect(err).To(MatchError(ContainSubstring("still being computed")))
+		g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	t.Run("Missing result", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := Analyze(ctx, &fakeClient{}, Config{})
+		g.Expect(err).To(MatchError(ContainSubstring("partition load is not available")))
+	})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partitionload
+
+import (
+	"sort"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Resources lists the resources partition load is analyzed for in the order they are reported.
+var Resources = []types.ResourceType{
+	types.ResourceTypeCPU,
+	types.ResourceTypeDisk,
+	types.ResourceTypeNetworkIn,
+	types.ResourceTypeNetworkOut,
+}
+
+// Load returns the load of the partition for the resource.
+func Load(p types.PartitionLoad, r types.ResourceType) float64 {
+	switch r {
+	case types.ResourceTypeCPU:
+		return p.CPU
+	case types.ResourceTypeDisk:
+		return p.Disk
+	case types.ResourceTypeNetworkIn:
+		return p.NetworkIn
+	case types.ResourceTypeNetworkOut:
+		return p.NetworkOut
+	case types.ResourceTypeUndefined:
+		fallthrough
+	default:
+		return 0
+	}
+}
+
+// Skew describes how unevenly a resource is distributed among a set of values.
+type Skew struct {
+	Resource types.ResourceType `json:"resource"`
+	Total    float64            `json:"total"`
+	Mean     float64            `json:"mean"`
+	Max      float64            `json:"max"`
+	// MaxToMean is the ratio of the maximum and the mean value. It is 1 for perfectly even distribution.
+	MaxToMean float64 `json:"maxToMean"`
+	// Gini is the Gini coefficient of the values between 0 (perfectly even) and 1 (all load on one item).
+	Gini float64 `json:"gini"`
+}
+
+// NewSkew returns the skew of the values of the resource.
+func NewSkew(r types.ResourceType, values []float64) Skew {
+	s := Skew{Resource: r}
+	if len(values) == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.Total += v
+		if v > s.Max {
+			s.Max = v
+		}
+	}
+	s.Mean = s.Total / float64(len(values))
+	if s.Mean > 0 {
+		s.MaxToMean = s.Max / s.Mean
+	}
+	s.Gini = Gini(values)
+	return s
+}
+
+// Gini returns the Gini coefficient of the values. It returns 0 for empty input or if all the values are 0.
+func Gini(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	sorted := make([]float64, n)
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var sum, weighted float64
+	for i, v := range sorted {
+		sum += v
+		weighted += float64(i+1) * v
+	}
+	if sum == 0 {
+		return 0
+	}
+	return 2*weighted/(float64(n)*sum) - float64(n+1)/float64(n)
+}
@@ -0,0 +1,353 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultTolerance = 0.2
+
+	ImbalanceReplicas ImbalanceKind = "replicas"
+	ImbalanceLeaders  ImbalanceKind = "leaders"
+)
+
+// Client is implemented by clients which are able to retrieve the Kafka cluster state and the cluster load
+// from Cruise Control.
+type Client interface {
+	KafkaClusterState(ctx context.Context, r *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error)
+	KafkaClusterLoad(ctx context.Context, r *api.KafkaClusterLoadRequest) (*api.KafkaClusterLoadResponse, error)
+}
+
+// Config contains the configuration parameters of the placement audit.
+type Config struct {
+	// Tolerance is the allowed relative deviation of the replica and leader count of a rack from the count
+	// expected based on the number of its brokers. DefaultTolerance is used if not set.
+	Tolerance float64
+}
+
+// RackSummary is the number of brokers, replicas and leaders in a rack.
+type RackSummary struct {
+	Rack     string  `json:"rack"`
+	Brokers  []int32 `json:"brokers"`
+	Replicas int     `json:"replicas"`
+	Leaders  int     `json:"leaders"`
+}
+
+// RackViolation is a partition with more replicas in a rack than allowed by rack-awareness.
+type RackViolation struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas"`
+	Rack      string  `json:"rack"`
+	// Count is the number of replicas in the rack.
+	Count int `json:"count"`
+	// MaxAllowed is the maximum number of replicas allowed in a rack which is more than one only if the replication
+	// factor of the partition is bigger than the number of racks.
+	MaxAllowed int `json:"maxAllowed"`
+}
+
+// BrokerSetViolation is a partition with replicas in more than one broker set.
+type BrokerSetViolation struct {
+	Topic      string   `json:"topic"`
+	Partition  int32    `json:"partition"`
+	Replicas   []int32  `json:"replicas"`
+	BrokerSets []string `json:"brokerSets"`
+}
+
+// ImbalanceKind is the kind of the replicas counted by a RackImbalance.
+type ImbalanceKind string
+
+// RackImbalance is a rack holding more or less replicas or leaders than expected based on the number of its brokers.
+type RackImbalance struct {
+	Rack     string        `json:"rack"`
+	Kind     ImbalanceKind `json:"kind"`
+	Count    int           `json:"count"`
+	Expected float64       `json:"expected"`
+	// Deviation is the relative deviation of the count from the expected count.
+	Deviation float64 `json:"deviation"`
+}
+
+// Concentrated returns true if the rack holds more replicas or leaders than expected.
+func (i RackImbalance) Concentrated() bool {
+	return i.Deviation > 0
+}
+
+// Report is the result of the placement audit.
+type Report struct {
+	Racks               []RackSummary        `json:"racks"`
+	RackViolations      []RackViolation      `json:"rackViolations"`
+	BrokerSetViolations []BrokerSetViolation `json:"brokerSetViolations"`
+	Imbalances          []RackImbalance      `json:"imbalances"`
+	// UnknownBrokers are the brokers hosting replicas which are missing from the cluster load.
+	UnknownBrokers []int32 `json:"unknownBrokers,omitempty"`
+	// BrokersWithoutRack are the brokers without rack information. They are left out of the rack checks, which are
+	// skipped entirely if none of the brokers has rack information.
+	BrokersWithoutRack []int32 `json:"brokersWithoutRack,omitempty"`
+	// distributionGoal is set if rack-awareness cannot be satisfied for some partitions due to their
+	// replication factor being bigger than the number of racks.
+	distributionGoal bool
+}
+
+// LeaderConcentrations returns the racks holding more leaders than expected.
+func (r *Report) LeaderConcentrations() []RackImbalance {
+	result := make([]RackImbalance, 0)
+	for _, i := range r.Imbalances {
+		if i.Kind == ImbalanceLeaders && i.Concentrated() {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Clean returns true if no violations or imbalances were found.
+func (r *Report) Clean() bool {
+	return len(r.RackViolations) == 0 && len(r.BrokerSetViolations) == 0 && len(r.Imbalances) == 0
+}
+
+// SuggestedRebalance returns a dry-run rebalance request with the goals fixing the rack and broker set
+// violations or nil if there are none. RackAwareDistributionGoal is used instead of RackAwareGoal if the
+// replication factor of a violating partition is bigger than the number of racks.
+func (r *Report) SuggestedRebalance() *api.RebalanceRequest {
+	goals := make([]types.Goal, 0)
+	if len(r.RackViolations) > 0 {
+		if r.distributionGoal {
+			goals = append(goals, types.RackAwareDistributionGoal)
+		} else {
+			goals = append(goals, types.RackAwareGoal)
+		}
+	}
+	if len(r.BrokerSetViolations) > 0 {
+		goals = append(goals, types.BrokerSetAwareGoal)
+	}
+	if len(goals) == 0 {
+		return nil
+	}
+
+	req := api.RebalanceRequestWithDefaults()
+	req.Goals = goals
+	req.DryRun = true
+	req.Reason = fmt.Sprintf("fix %d rack-awareness and %d broker set violations",
+		len(r.RackViolations), len(r.BrokerSetViolations))
+	return req
+}
+
+// Run retrieves the Kafka cluster state and the cluster load from Cruise Control and audits the replica placement.
+func Run(ctx context.Context, client Client, config Config) (*Report, error) {
+	state, err := client.KafkaClusterState(ctx, api.KafkaClusterStateRequestWithDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster state: %w", err)
+	}
+	load, err := client.KafkaClusterLoad(ctx, api.KafkaClusterLoadRequestWithDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster load: %w", err)
+	}
+	return Audit(state.Result, load.Result, config)
+}
+
+// Audit checks the replica placement of the partitions in the Kafka cluster state using the racks of the brokers
+// from the cluster load. The Kafka cluster state needs to be verbose to include every partition. It returns an error
+// if either the state or the load is missing, e.g. because Cruise Control returned an in progress response.
+func Audit(state *types.KafkaClusterState, load *types.BrokerStats, config Config) (*Report, error) {
+	if state == nil {
+		return nil, errors.New("state of the Kafka cluster is not available")
+	}
+	if load == nil {
+		return nil, errors.New("load of the Kafka cluster is not available")
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DefaultTolerance
+	}
+
+	report := &Report{
+		Racks:               make([]RackSummary, 0),
+		RackViolations:      make([]RackViolation, 0),
+		BrokerSetViolations: make([]BrokerSetViolation, 0),
+		Imbalances:          make([]RackImbalance, 0),
+	}
+
+	// Brokers without rack information are known but are not placed in any rack.
+	known := make(map[int32]bool, len(load.Brokers))
+	rackOf := make(map[int32]string, len(load.Brokers))
+	racks := make(map[string]*RackSummary)
+	for _, b := range load.Brokers {
+		known[b.Broker] = true
+		if b.Rack == "" {
+			report.BrokersWithoutRack = append(report.BrokersWithoutRack, b.Broker)
+			continue
+		}
+		rackOf[b.Broker] = b.Rack
+		rack, ok := racks[b.Rack]
+		if !ok {
+			rack = &RackSummary{Rack: b.Rack}
+			racks[b.Rack] = rack
+		}
+		rack.Brokers = append(rack.Brokers, b.Broker)
+	}
+
+	brokerSetOf := make(map[int32]string, len(state.KafkaBrokerState.BrokerSetByBrokerID))
+	for id, set := range state.KafkaBrokerState.BrokerSetByBrokerID {
+		if broker, err := strconv.ParseInt(id, 10, 32); err == nil {
+			brokerSetOf[int32(broker)] = set
+		}
+	}
+
+	unknown := make(map[int32]bool)
+	for _, p := range partitions(state.KafkaPartitionState) {
+		replicasByRack := make(map[string]int)
+		for _, b := range p.Replicas {
+			if !known[b] {
+				unknown[b] = true
+			}
+			rack, ok := rackOf[b]
+			if !ok {
+				continue
+			}
+			replicasByRack[rack]++
+			racks[rack].Replicas++
+		}
+		if rack, ok := rackOf[p.Leader]; ok {
+			racks[rack].Leaders++
+		}
+
+		maxAllowed := len(p.Replicas)
+		if len(racks) > 0 {
+			maxAllowed = int(math.Ceil(float64(len(p.Replicas)) / float64(len(racks))))
+		}
+		for _, rack := range sortedKeys(replicasByRack) {
+			if count := replicasByRack[rack]; count > maxAllowed {
+				report.RackViolations = append(report.RackViolations, RackViolation{
+					Topic:      p.Topic,
+					Partition:  p.Partition,
+					Replicas:   p.Replicas,
+					Rack:       rack,
+					Count:      count,
+					MaxAllowed: maxAllowed,
+				})
+				if maxAllowed > 1 {
+					report.distributionGoal = true
+				}
+			}
+		}
+
+		if len(brokerSetOf) > 0 {
+			sets := make(map[string]int)
+			for _, b := range p.Replicas {
+				if set, ok := brokerSetOf[b]; ok {
+					sets[set]++
+				}
+			}
+			if len(sets) > 1 {
+				report.BrokerSetViolations = append(report.BrokerSetViolations, BrokerSetViolation{
+					Topic:      p.Topic,
+					Partition:  p.Partition,
+					Replicas:   p.Replicas,
+					BrokerSets: sortedKeys(sets),
+				})
+			}
+		}
+	}
+
+	var totalReplicas, totalLeaders int
+	for _, rack := range racks {
+		totalReplicas += rack.Replicas
+		totalLeaders += rack.Leaders
+	}
+	rackedBrokers := len(rackOf)
+	for _, name := range sortedKeys(racks) {
+		rack := racks[name]
+		sort.Slice(rack.Brokers, func(i, j int) bool { return rack.Brokers[i] < rack.Brokers[j] })
+		report.Racks = append(report.Racks, *rack)
+
+		share := float64(len(rack.Brokers)) / float64(rackedBrokers)
+		for _, c := range []struct {
+			kind  ImbalanceKind
+			count int
+			total int
+		}{
+			{ImbalanceReplicas, rack.Replicas, totalReplicas},
+			{ImbalanceLeaders, rack.Leaders, totalLeaders},
+		} {
+			expected := float64(c.total) * share
+			if expected == 0 {
+				continue
+			}
+			deviation := (float64(c.count) - expected) / expected
+			if math.Abs(deviation) > config.Tolerance {
+				report.Imbalances = append(report.Imbalances, RackImbalance{
+					Rack:      name,
+					Kind:      c.kind,
+					Count:     c.count,
+					Expected:  expected,
+					Deviation: deviation,
+				})
+			}
+		}
+	}
+
+	for b := range unknown {
+		report.UnknownBrokers = append(report.UnknownBrokers, b)
+	}
+	sort.Slice(report.UnknownBrokers, func(i, j int) bool { return report.UnknownBrokers[i] < report.UnknownBrokers[j] })
+	sort.Slice(report.BrokersWithoutRack, func(i, j int) bool {
+		return report.BrokersWithoutRack[i] < report.BrokersWithoutRack[j]
+	})
+
+	return report, nil
+}
+
+// partitions returns every partition of the Kafka cluster state once.
+func partitions(s types.KafkaPartitionState) []types.PartitionState {
+	seen := make(map[string]bool)
+	result := make([]types.PartitionState, 0)
+	for _, list := range [][]types.PartitionState{
+		s.Offline, s.WithOfflineReplicas, s.UnderReplicatedPartitions, s.UnderMinISR, s.Other,
+	} {
+		for _, p := range list {
+			key := fmt.Sprintf("%s-%d", p.Topic, p.Partition)
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, p)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Topic != result[j].Topic {
+			return result[i].Topic < result[j].Topic
+		}
+		return result[i].Partition < result[j].Partition
+	})
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
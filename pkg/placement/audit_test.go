@@ -0,0 +1,134 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestAudit(t *testing.T) {
+	load := &types.BrokerStats{
+		Brokers: []types.BrokerLoadStats{
+			{Broker: 0, Rack: "a"},
+			{Broker: 1, Rack: "a"},
+			{Broker: 2, Rack: "b"},
+			{Broker: 3, Rack: "b"},
+		},
+	}
+
+	t.Run("Rack and broker set violations", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := &types.KafkaClusterState{
+			KafkaBrokerState: types.KafkaBrokerState{
+				BrokerSetByBrokerID: map[string]string{"0": "x", "1": "y", "2": "x", "3": "y"},
+			},
+			KafkaPartitionState: types.KafkaPartitionState{
+				Other: []types.PartitionState{
+					{Topic: "foo", Partition: 0, Leader: 0, Replicas: []int32{0, 1}},
+					{Topic: "foo", Partition: 1, Leader: 0, Replicas: []int32{0, 2}},
+					{Topic: "foo", Partition: 2, Leader: 0, Replicas: []int32{0, 2}},
+					{Topic: "foo", Partition: 3, Leader: 1, Replicas: []int32{1, 3}},
+				},
+			},
+		}
+
+		report, err := Audit(state, load, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(report.RackViolations).To(HaveLen(1))
+		g.Expect(report.RackViolations[0].Partition).To(Equal(int32(0)))
+		g.Expect(report.RackViolations[0].Rack).To(Equal("a"))
+		g.Expect(report.BrokerSetViolations).To(HaveLen(1))
+		g.Expect(report.BrokerSetViolations[0].BrokerSets).To(Equal([]string{"x", "y"}))
+
+		concentrations := report.LeaderConcentrations()
+		g.Expect(concentrations).To(HaveLen(1))
+		g.Expect(concentrations[0].Rack).To(Equal("a"))
+		g.Expect(concentrations[0].Count).To(Equal(4))
+
+		req := report.SuggestedRebalance()
+		g.Expect(req).NotTo(BeNil())
+		g.Expect(req.DryRun).To(BeTrue())
+		g.Expect(req.Goals).To(Equal([]types.Goal{types.RackAwareGoal, types.BrokerSetAwareGoal}))
+	})
+
+	t.Run("Rack-aware placement", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := &types.KafkaClusterState{
+			KafkaPartitionState: types.KafkaPartitionState{
+				Other: []types.PartitionState{
+					{Topic: "foo", Partition: 0, Leader: 0, Replicas: []int32{0, 2}},
+					{Topic: "foo", Partition: 1, Leader: 3, Replicas: []int32{3, 1}},
+				},
+			},
+		}
+
+		report, err := Audit(state, load, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(report.Clean()).To(BeTrue())
+		g.Expect(report.SuggestedRebalance()).To(BeNil())
+	})
+	t.Run("Brokers without rack", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := &types.KafkaClusterState{
+			KafkaPartitionState: types.KafkaPartitionState{
+				Other: []types.PartitionState{
+					{Topic: "foo", Partition: 0, Leader: 0, Replicas: []int32{0, 1}},
+					{Topic: "foo", Partition: 1, Leader: 0, Replicas: []int32{0, 1}},
+					{Topic: "foo", Partition: 2, Leader: 2, Replicas: []int32{2, 1}},
+				},
+			},
+		}
+
+		report, err := Audit(state, &types.BrokerStats{Brokers: []types.BrokerLoadStats{
+			{Broker: 0}, {Broker: 1}, {Broker: 2},
+		}}, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Clean()).To(BeTrue())
+		g.Expect(report.Racks).To(BeEmpty())
+		g.Expect(report.BrokersWithoutRack).To(Equal([]int32{0, 1, 2}))
+		g.Expect(report.UnknownBrokers).To(BeEmpty())
+
+		// Only the brokers with rack information are checked.
+		report, err = Audit(state, &types.BrokerStats{Brokers: []types.BrokerLoadStats{
+			{Broker: 0, Rack: "a"}, {Broker: 1}, {Broker: 2, Rack: "b"},
+		}}, Config{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.RackViolations).To(BeEmpty())
+		g.Expect(report.Racks).To(HaveLen(2))
+		g.Expect(report.BrokersWithoutRack).To(Equal([]int32{1}))
+		g.Expect(report.LeaderConcentrations()).To(HaveLen(1))
+		g.Expect(report.LeaderConcentrations()[0].Rack).To(Equal("a"))
+	})
+
+	t.Run("Missing state or load", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := Audit(nil, load, Config{})
+		g.Expect(err).To(HaveOccurred())
+		_, err = Audit(&types.KafkaClusterState{}, nil, Config{})
+		g.Expect(err).To(HaveOccurred())
+	})
+}
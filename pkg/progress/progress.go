@@ -0,0 +1,222 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"math"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	PhaseUndefined Phase = iota
+	PhaseInterBrokerReplicaMovement
+	PhaseIntraBrokerReplicaMovement
+	PhaseLeadershipMovement
+)
+
+// Phase is an enum for the phases of a Cruise Control execution. Phases are executed in the order of their values.
+type Phase int8
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseInterBrokerReplicaMovement:
+		return "INTER_BROKER_REPLICA_MOVEMENT"
+	case PhaseIntraBrokerReplicaMovement:
+		return "INTRA_BROKER_REPLICA_MOVEMENT"
+	case PhaseLeadershipMovement:
+		return "LEADERSHIP_MOVEMENT"
+	case PhaseUndefined:
+		fallthrough
+	default:
+		return types.Undefined
+	}
+}
+
+// PhaseProgress holds the progress of a single phase of the execution.
+type PhaseProgress struct {
+	Phase Phase
+
+	TotalMovements      int32
+	FinishedMovements   int32
+	InProgressMovements int32
+	PendingMovements    int32
+
+	// Amount of data to move in MB. It is always zero for leadership movements.
+	TotalDataMB    int64
+	FinishedDataMB int64
+
+	// Throughput measured over the sampling window.
+	ThroughputMBps     float64
+	MovementsPerMinute float64
+
+	// Estimated time remaining for the phase, only valid if ETAKnown is true.
+	ETA      time.Duration
+	ETAKnown bool
+}
+
+// Completed returns true if there is no movement left in the phase.
+func (p PhaseProgress) Completed() bool {
+	return p.FinishedMovements >= p.TotalMovements
+}
+
+// Progress holds the progress of an ongoing Cruise Control execution.
+type Progress struct {
+	Time time.Time
+
+	State               types.ExecutorStateType
+	TriggeredUserTaskID string
+
+	InterBroker PhaseProgress
+	IntraBroker PhaseProgress
+	Leadership  PhaseProgress
+
+	// Estimated time remaining for the whole execution, only valid if ETAKnown is true.
+	ETA      time.Duration
+	ETAKnown bool
+
+	// Stalled is true if no progress was made for at least the configured number of consecutive samples.
+	Stalled bool
+	// Number of consecutive samples without progress.
+	IntervalsWithoutProgress int
+}
+
+// InProgress returns true if Cruise Control is executing a task.
+func (p Progress) InProgress() bool {
+	return p.State != types.ExecutorStateTypeNoTaskInProgress && p.State != types.ExecutorStateTypeUndefined
+}
+
+// Phases returns the progress of the phases in execution order.
+func (p Progress) Phases() []PhaseProgress {
+	return []PhaseProgress{p.InterBroker, p.IntraBroker, p.Leadership}
+}
+
+type sample struct {
+	time  time.Time
+	state types.ExecutorState
+}
+
+func phasesOf(s types.ExecutorState) (PhaseProgress, PhaseProgress, PhaseProgress) {
+	inter := PhaseProgress{
+		Phase:               PhaseInterBrokerReplicaMovement,
+		TotalMovements:      s.NumTotalPartitionMovements,
+		FinishedMovements:   s.NumFinishedPartitionMovements,
+		InProgressMovements: s.NumInProgressPartitionMovements,
+		PendingMovements:    s.NumPendingPartitionMovements,
+		TotalDataMB:         s.TotalDataToMove,
+		FinishedDataMB:      s.FinishedDataMovement,
+	}
+	intra := PhaseProgress{
+		Phase:               PhaseIntraBrokerReplicaMovement,
+		TotalMovements:      s.NumTotalIntraBrokerPartitionMovements,
+		FinishedMovements:   s.NumFinishedIntraBrokerPartitionMovements,
+		InProgressMovements: s.NumInProgressIntraBrokerPartitionMovements,
+		PendingMovements:    s.NumPendingIntraBrokerPartitionMovements,
+		TotalDataMB:         s.TotalIntraBrokerDataToMove,
+		FinishedDataMB:      s.FinishedIntraBrokerDataMovement,
+	}
+	leadership := PhaseProgress{
+		Phase:             PhaseLeadershipMovement,
+		TotalMovements:    s.NumTotalLeadershipMovements,
+		FinishedMovements: s.NumFinishedLeadershipMovements,
+		PendingMovements:  s.NumPendingLeadershipMovements,
+	}
+	return inter, intra, leadership
+}
+
+// estimate computes the throughput and ETA of the phase from its state at the beginning of the sampling window.
+func estimate(first, last PhaseProgress, elapsed time.Duration) PhaseProgress {
+	p := last
+	if p.Completed() {
+		p.ETAKnown = true
+		return p
+	}
+	if elapsed <= 0 {
+		return p
+	}
+
+	seconds := elapsed.Seconds()
+	p.ThroughputMBps = float64(last.FinishedDataMB-first.FinishedDataMB) / seconds
+	p.MovementsPerMinute = float64(last.FinishedMovements-first.FinishedMovements) / seconds * 60 //nolint:gomnd
+	// Finished counters going backwards, e.g. after Cruise Control restarted the execution, do not mean
+	// negative throughput.
+	p.ThroughputMBps = math.Max(p.ThroughputMBps, 0)
+	p.MovementsPerMinute = math.Max(p.MovementsPerMinute, 0)
+
+	remainingData := last.TotalDataMB - last.FinishedDataMB
+	remainingMovements := last.TotalMovements - last.FinishedMovements
+	switch {
+	case remainingData > 0 && p.ThroughputMBps > 0:
+		p.ETA = time.Duration(float64(remainingData) / p.ThroughputMBps * float64(time.Second))
+		p.ETAKnown = true
+	case remainingMovements > 0 && p.MovementsPerMinute > 0:
+		p.ETA = time.Duration(float64(remainingMovements) / p.MovementsPerMinute * float64(time.Minute))
+		p.ETAKnown = true
+	}
+	return p
+}
+
+// compute returns the progress of the execution based on the samples collected in the sampling window.
+func compute(samples []sample) Progress {
+	if len(samples) == 0 {
+		return Progress{}
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.time.Sub(first.time)
+
+	fInter, fIntra, fLeadership := phasesOf(first.state)
+	lInter, lIntra, lLeadership := phasesOf(last.state)
+
+	p := Progress{
+		Time:                last.time,
+		State:               last.state.State,
+		TriggeredUserTaskID: last.state.TriggeredUserTaskID,
+		InterBroker:         estimate(fInter, lInter, elapsed),
+		IntraBroker:         estimate(fIntra, lIntra, elapsed),
+		Leadership:          estimate(fLeadership, lLeadership, elapsed),
+		ETAKnown:            true,
+	}
+
+	for _, phase := range p.Phases() {
+		if phase.Completed() {
+			continue
+		}
+		// Leadership movements which have not started yet are left out from the estimation as they complete
+		// orders of magnitude faster than replica movements.
+		leadershipStarted := last.state.State == types.ExecutorStateTypeLeaderMovementTaskInProgress
+		if phase.Phase == PhaseLeadershipMovement && !phase.ETAKnown && !leadershipStarted {
+			continue
+		}
+		if !phase.ETAKnown {
+			p.ETAKnown = false
+			p.ETA = 0
+			break
+		}
+		p.ETA += phase.ETA
+	}
+
+	return p
+}
+
+func progressed(prev, cur types.ExecutorState) bool {
+	return cur.FinishedDataMovement != prev.FinishedDataMovement ||
+		cur.NumFinishedPartitionMovements != prev.NumFinishedPartitionMovements ||
+		cur.FinishedIntraBrokerDataMovement != prev.FinishedIntraBrokerDataMovement ||
+		cur.NumFinishedIntraBrokerPartitionMovements != prev.NumFinishedIntraBrokerPartitionMovements ||
+		cur.NumFinishedLeadershipMovements != prev.NumFinishedLeadershipMovements
+}
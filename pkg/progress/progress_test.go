@@ -0,0 +1,183 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name        string
+		first, last PhaseProgress
+		elapsed     time.Duration
+		throughput  float64
+		perMinute   float64
+		eta         time.Duration
+		etaKnown    bool
+	}{
+		{
+			name:     "Zero elapsed time",
+			first:    PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			last:     PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			elapsed:  0,
+			etaKnown: false,
+		},
+		{
+			name:     "Zero total",
+			elapsed:  time.Minute,
+			etaKnown: true,
+		},
+		{
+			name:       "Data based ETA",
+			first:      PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			last:       PhaseProgress{TotalMovements: 10, FinishedMovements: 4, TotalDataMB: 100, FinishedDataMB: 40},
+			elapsed:    10 * time.Second,
+			throughput: 2,
+			perMinute:  12,
+			eta:        30 * time.Second,
+			etaKnown:   true,
+		},
+		{
+			name:      "Movement based ETA",
+			first:     PhaseProgress{TotalMovements: 10, FinishedMovements: 2},
+			last:      PhaseProgress{TotalMovements: 10, FinishedMovements: 4},
+			elapsed:   time.Minute,
+			perMinute: 2,
+			eta:       3 * time.Minute,
+			etaKnown:  true,
+		},
+		{
+			name:     "No progress",
+			first:    PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			last:     PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			elapsed:  time.Minute,
+			etaKnown: false,
+		},
+		{
+			name:     "Progress going backwards",
+			first:    PhaseProgress{TotalMovements: 10, FinishedMovements: 6, TotalDataMB: 100, FinishedDataMB: 60},
+			last:     PhaseProgress{TotalMovements: 10, FinishedMovements: 2, TotalDataMB: 100, FinishedDataMB: 20},
+			elapsed:  time.Minute,
+			etaKnown: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			p := estimate(test.first, test.last, test.elapsed)
+			g.Expect(p.ThroughputMBps).To(BeNumerically("~", test.throughput))
+			g.Expect(p.MovementsPerMinute).To(BeNumerically("~", test.perMinute))
+			g.Expect(p.ETAKnown).To(Equal(test.etaKnown))
+			g.Expect(p.ETA).To(BeNumerically("~", test.eta, time.Millisecond))
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	running := func(finishedMB int64, finished int32) types.ExecutorState {
+		return types.ExecutorState{
+			State:                         types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress,
+			TriggeredUserTaskID:           "task",
+			NumTotalPartitionMovements:    10,
+			NumFinishedPartitionMovements: finished,
+			TotalDataToMove:               100,
+			FinishedDataMovement:          finishedMB,
+			NumTotalLeadershipMovements:   5,
+		}
+	}
+
+	t.Run("No samples", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		p := compute(nil)
+		g.Expect(p.InProgress()).To(BeFalse())
+		g.Expect(p.ETAKnown).To(BeFalse())
+	})
+
+	t.Run("Single sample", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		p := compute([]sample{{time: start, state: running(20, 2)}})
+		g.Expect(p.InProgress()).To(BeTrue())
+		g.Expect(p.ETAKnown).To(BeFalse())
+	})
+
+	t.Run("Pending leadership movements are left out", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		p := compute([]sample{
+			{time: start, state: running(20, 2)},
+			{time: start.Add(10 * time.Second), state: running(40, 4)},
+		})
+		g.Expect(p.ETAKnown).To(BeTrue())
+		g.Expect(p.ETA).To(BeNumerically("~", 30*time.Second, time.Millisecond))
+	})
+
+	t.Run("Zero total", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := types.ExecutorState{State: types.ExecutorStateTypeStartingExecution}
+		p := compute([]sample{{time: start, state: state}, {time: start.Add(time.Second), state: state}})
+		g.Expect(p.ETAKnown).To(BeTrue())
+		g.Expect(p.ETA).To(BeZero())
+	})
+}
+
+func TestTrackerAdd(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := func(task string, finishedMB int64) types.ExecutorState {
+		return types.ExecutorState{
+			State:                      types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress,
+			TriggeredUserTaskID:        task,
+			NumTotalPartitionMovements: 10,
+			TotalDataToMove:            100,
+			FinishedDataMovement:       finishedMB,
+		}
+	}
+
+	tracker := NewTracker(nil, Config{WindowSize: 3, StallIntervals: 2})
+	tracker.Add(start, state("a", 10))
+	p := tracker.Add(start.Add(time.Second), state("a", 10))
+	g.Expect(p.IntervalsWithoutProgress).To(Equal(1))
+	g.Expect(p.Stalled).To(BeFalse())
+
+	p = tracker.Add(start.Add(2*time.Second), state("a", 10))
+	g.Expect(p.Stalled).To(BeTrue())
+
+	p = tracker.Add(start.Add(3*time.Second), state("a", 30))
+	g.Expect(p.Stalled).To(BeFalse())
+	g.Expect(tracker.samples).To(HaveLen(3))
+	g.Expect(p.InterBroker.ThroughputMBps).To(BeNumerically("~", 10))
+
+	// A new execution discards the samples of the previous one.
+	p = tracker.Add(start.Add(4*time.Second), state("b", 0))
+	g.Expect(tracker.samples).To(HaveLen(1))
+	g.Expect(p.IntervalsWithoutProgress).To(BeZero())
+	g.Expect(p.ETAKnown).To(BeFalse())
+}
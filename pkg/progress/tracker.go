@@ -0,0 +1,164 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultSampleInterval = 10 * time.Second
+	DefaultWindowSize     = 30
+	DefaultStallIntervals = 30
+)
+
+// StateGetter is implemented by clients which are able to retrieve the Cruise Control state.
+type StateGetter interface {
+	State(ctx context.Context, r *api.StateRequest) (*api.StateResponse, error)
+}
+
+// Config contains the configuration parameters for the Tracker.
+type Config struct {
+	// Interval between sampling the executor state. DefaultSampleInterval is used if not set.
+	Interval time.Duration
+	// Number of samples the throughput is computed from. DefaultWindowSize is used if not set.
+	WindowSize int
+	// Number of consecutive samples without progress after the execution is considered stalled.
+	// DefaultStallIntervals is used if not set.
+	StallIntervals int
+	// OnUpdate is called with the computed progress after every sample.
+	OnUpdate func(Progress)
+}
+
+// Tracker samples the executor state of Cruise Control and estimates the progress of the ongoing execution.
+type Tracker struct {
+	client StateGetter
+	config Config
+
+	mu                       sync.RWMutex
+	samples                  []sample
+	intervalsWithoutProgress int
+	latest                   Progress
+}
+
+// NewTracker returns a new Tracker using the provided client and configuration.
+func NewTracker(client StateGetter, config Config) *Tracker {
+	if config.Interval <= 0 {
+		config.Interval = DefaultSampleInterval
+	}
+	if config.WindowSize < 2 { //nolint:gomnd
+		config.WindowSize = DefaultWindowSize
+	}
+	if config.StallIntervals <= 0 {
+		config.StallIntervals = DefaultStallIntervals
+	}
+	return &Tracker{
+		client: client,
+		config: config,
+	}
+}
+
+// Progress returns the latest computed progress.
+func (t *Tracker) Progress() Progress {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.latest
+}
+
+// Run samples the executor state until the context is cancelled.
+func (t *Tracker) Run(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := t.Sample(ctx); err != nil && ctx.Err() == nil {
+			log.Error(err, "failed to sample executor state")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample retrieves the executor state once and returns the updated progress.
+func (t *Tracker) Sample(ctx context.Context) (Progress, error) {
+	req := api.StateRequestWithDefaults()
+	req.Substates = []types.Substate{types.SubstateExecutor}
+
+	resp, err := t.client.State(ctx, req)
+	if err != nil {
+		return Progress{}, fmt.Errorf("failed to get executor state: %w", err)
+	}
+	if resp.Result == nil {
+		return Progress{}, errors.New("executor state is not available")
+	}
+
+	p := t.Add(time.Now(), resp.Result.ExecutorState)
+	if t.config.OnUpdate != nil {
+		t.config.OnUpdate(p)
+	}
+	return p, nil
+}
+
+// Add records a sample of the executor state taken at the given time and returns the updated progress.
+// Samples from a previous execution are discarded when a new execution is detected.
+func (t *Tracker) Add(now time.Time, state types.ExecutorState) Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n := len(t.samples); n > 0 {
+		prev := t.samples[n-1].state
+		if prev.TriggeredUserTaskID != state.TriggeredUserTaskID ||
+			prev.TriggeredSelfHealingTaskID != state.TriggeredSelfHealingTaskID {
+			t.samples = nil
+			t.intervalsWithoutProgress = 0
+		} else if progressed(prev, state) {
+			t.intervalsWithoutProgress = 0
+		} else {
+			t.intervalsWithoutProgress++
+		}
+	}
+
+	t.samples = append(t.samples, sample{time: now, state: state})
+	if len(t.samples) > t.config.WindowSize {
+		t.samples = t.samples[len(t.samples)-t.config.WindowSize:]
+	}
+
+	p := compute(t.samples)
+	if p.InProgress() {
+		p.IntervalsWithoutProgress = t.intervalsWithoutProgress
+		p.Stalled = t.intervalsWithoutProgress >= t.config.StallIntervals
+	} else {
+		t.intervalsWithoutProgress = 0
+	}
+	t.latest = p
+	return p
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicationfactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// Client is implemented by clients which are able to retrieve the Kafka cluster state and the partition load
+// from Cruise Control.
+type Client interface {
+	KafkaClusterState(ctx context.Context, r *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error)
+	KafkaPartitionLoad(ctx context.Context, r *api.KafkaPartitionLoadRequest) (*api.KafkaPartitionLoadResponse, error)
+}
+
+// TopicChange is the replication factor change of a topic.
+type TopicChange struct {
+	Topic      string `json:"topic"`
+	Partitions int    `json:"partitions"`
+	// Lowest and highest replication factor of the partitions of the topic.
+	MinCurrentRF int32 `json:"minCurrentRF"`
+	MaxCurrentRF int32 `json:"maxCurrentRF"`
+	TargetRF     int32 `json:"targetRF"`
+	// MinISR is the highest min.insync.replicas of the partitions of the topic.
+	MinISR int32 `json:"minISR"`
+	// SizeMB is the size of a single replica of every partition of the topic.
+	SizeMB float64 `json:"sizeMB"`
+	// ExtraDiskMB is the disk space needed by the new replicas. It is negative if replicas are removed.
+	ExtraDiskMB float64 `json:"extraDiskMB"`
+	// Problems which make the change unsafe or impossible.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Unchanged returns true if every partition of the topic already has the target replication factor.
+func (t TopicChange) Unchanged() bool {
+	return t.MinCurrentRF == t.TargetRF && t.MaxCurrentRF == t.TargetRF
+}
+
+// Plan is the preview of changing the replication factor of the topics matching a pattern.
+type Plan struct {
+	Pattern  string        `json:"pattern"`
+	TargetRF int32         `json:"targetRF"`
+	Brokers  []int32       `json:"brokers"`
+	Topics   []TopicChange `json:"topics"`
+	// ExtraDiskMBByBroker is the estimated disk space needed on each broker. New replicas are assumed to be spread
+	// evenly over the brokers which do not host the partition yet, and removed replicas evenly over the brokers
+	// which do. The actual placement is decided by Cruise Control.
+	ExtraDiskMBByBroker map[int32]float64 `json:"extraDiskMBByBroker"`
+}
+
+// Feasible returns true if none of the topics has problems.
+func (p *Plan) Feasible() bool {
+	for _, t := range p.Topics {
+		if len(t.Problems) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Changed returns the names of the topics whose replication factor needs to be changed.
+func (p *Plan) Changed() []string {
+	topics := make([]string, 0, len(p.Topics))
+	for _, t := range p.Topics {
+		if !t.Unchanged() {
+			topics = append(topics, t.Topic)
+		}
+	}
+	return topics
+}
+
+// ExtraDiskMB returns the disk space needed by the new replicas of every topic.
+func (p *Plan) ExtraDiskMB() float64 {
+	var total float64
+	for _, t := range p.Topics {
+		total += t.ExtraDiskMB
+	}
+	return total
+}
+
+// NewPlan retrieves the state and the load of the partitions of the topics matching the pattern from Cruise Control
+// and returns the preview of changing their replication factor to targetRF.
+func NewPlan(ctx context.Context, client Client, pattern string, targetRF int32) (*Plan, error) {
+	stateReq := api.KafkaClusterStateRequestWithDefaults()
+	stateReq.Topic = pattern
+	state, err := client.KafkaClusterState(ctx, stateReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster state: %w", err)
+	}
+
+	loadReq := api.KafkaPartitionLoadRequestWithDefaults()
+	loadReq.Topic = pattern
+	load, err := client.KafkaPartitionLoad(ctx, loadReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partition load: %w", err)
+	}
+	if load.Result == nil {
+		return nil, errors.New("partition load is missing from the response")
+	}
+
+	return Preview(state.Result, load.Result, pattern, targetRF)
+}
+
+// Preview returns the preview of changing the replication factor of the topics matching the pattern to targetRF
+// based on the Kafka cluster state and the partition load. Like Cruise Control, the pattern needs to match the
+// whole topic name. The Kafka cluster state needs to be verbose to include every partition.
+func Preview(state *types.KafkaClusterState, load *types.PartitionLoadState, pattern string, targetRF int32,
+) (*Plan, error) {
+	if state == nil {
+		return nil, errors.New("kafka cluster state is missing")
+	}
+	if targetRF < 1 {
+		return nil, fmt.Errorf("invalid replication factor %d: must be at least 1", targetRF)
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic pattern %q: %w", pattern, err)
+	}
+
+	plan := &Plan{
+		Pattern:             pattern,
+		TargetRF:            targetRF,
+		Brokers:             brokers(state),
+		Topics:              make([]TopicChange, 0),
+		ExtraDiskMBByBroker: make(map[int32]float64),
+	}
+	for _, b := range plan.Brokers {
+		plan.ExtraDiskMBByBroker[b] = 0
+	}
+
+	sizes := make(map[types.TopicPartition]float64)
+	if load != nil {
+		for _, r := range load.Records {
+			sizes[types.TopicPartition{Topic: r.Topic, Partition: r.Partition}] = r.Disk
+		}
+	}
+
+	changes := make(map[string]*TopicChange)
+	// A partition is listed in every list it belongs to, e.g. an under-replicated partition might be under
+	// min ISR and have offline replicas too, so each one is counted once.
+	seen := make(map[types.TopicPartition]bool)
+	ps := state.KafkaPartitionState
+	for _, partitions := range [][]types.PartitionState{
+		ps.Offline, ps.WithOfflineReplicas, ps.UnderReplicatedPartitions, ps.UnderMinISR, ps.Other,
+	} {
+		for _, p := range partitions {
+			tp := types.TopicPartition{Topic: p.Topic, Partition: p.Partition}
+			if seen[tp] || !re.MatchString(p.Topic) {
+				continue
+			}
+			seen[tp] = true
+			c, ok := changes[p.Topic]
+			if !ok {
+				c = &TopicChange{Topic: p.Topic, TargetRF: targetRF, MinCurrentRF: int32(len(p.Replicas))}
+				changes[p.Topic] = c
+			}
+			plan.addPartition(c, p, sizes[tp])
+		}
+	}
+
+	for _, c := range changes {
+		c.Problems = problems(c, len(plan.Brokers))
+		plan.Topics = append(plan.Topics, *c)
+	}
+	sort.Slice(plan.Topics, func(i, j int) bool { return plan.Topics[i].Topic < plan.Topics[j].Topic })
+
+	return plan, nil
+}
+
+func (p *Plan) addPartition(c *TopicChange, partition types.PartitionState, size float64) {
+	rf := int32(len(partition.Replicas))
+	c.Partitions++
+	c.SizeMB += size
+	c.MinCurrentRF = min(c.MinCurrentRF, rf)
+	c.MaxCurrentRF = max(c.MaxCurrentRF, rf)
+	c.MinISR = max(c.MinISR, partition.MinISRReplicas)
+
+	diff := p.TargetRF - rf
+	if diff == 0 {
+		return
+	}
+	extra := float64(diff) * size
+	c.ExtraDiskMB += extra
+
+	hosts := make(map[int32]bool, len(partition.Replicas))
+	for _, b := range partition.Replicas {
+		hosts[b] = true
+	}
+	candidates := make([]int32, 0, len(p.Brokers))
+	for _, b := range p.Brokers {
+		// New replicas go to brokers not hosting the partition, removed ones are taken from brokers hosting it.
+		if hosts[b] == (diff < 0) {
+			candidates = append(candidates, b)
+		}
+	}
+	for _, b := range candidates {
+		p.ExtraDiskMBByBroker[b] += extra / float64(len(candidates))
+	}
+}
+
+func problems(c *TopicChange, brokers int) []string {
+	problems := make([]string, 0)
+	if int(c.TargetRF) > brokers {
+		problems = append(problems, fmt.Sprintf("replication factor %d exceeds the number of brokers %d",
+			c.TargetRF, brokers))
+	}
+	if c.MinISR > 0 && c.TargetRF < c.MinISR {
+		problems = append(problems, fmt.Sprintf("replication factor %d is below min.insync.replicas %d, "+
+			"producers using acks=all would fail", c.TargetRF, c.MinISR))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// brokers returns the IDs of the brokers in the Kafka cluster state.
+func brokers(state *types.KafkaClusterState) []int32 {
+	ids := make([]int32, 0, len(state.KafkaBrokerState.ReplicaCountByBrokerID))
+	for id := range state.KafkaBrokerState.ReplicaCountByBrokerID {
+		if i, err := strconv.ParseInt(id, 10, 32); err == nil {
+			ids = append(ids, int32(i))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicationfactor
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+type fakeClient struct {
+	state *types.KafkaClusterState
+	load  *types.PartitionLoadState
+}
+
+func (f *fakeClient) KafkaClusterState(_ context.Context, _ *api.KafkaClusterStateRequest) (*api.KafkaClusterStateResponse, error) { //nolint:lll
+	return &api.KafkaClusterStateResponse{Result: f.state}, nil
+}
+
+func (f *fakeClient) KafkaPartitionLoad(_ context.Context, _ *api.KafkaPartitionLoadRequest) (*api.KafkaPartitionLoadResponse, error) { //nolint:lll
+	return &api.KafkaPartitionLoadResponse{Result: f.load}, nil
+}
+
+func testState() *types.KafkaClusterState {
+	return &types.KafkaClusterState{
+		KafkaBrokerState: types.KafkaBrokerState{
+			ReplicaCountByBrokerID: map[string]int32{"0": 3, "1": 3, "2": 2, "3": 1},
+		},
+		KafkaPartitionState: types.KafkaPartitionState{
+			Other: []types.PartitionState{
+				{Topic: "orders", Partition: 0, Replicas: []int32{0, 1}, MinISRReplicas: 2},
+				{Topic: "orders", Partition: 1, Replicas: []int32{1, 2}, MinISRReplicas: 2},
+				{Topic: "payments", Partition: 0, Replicas: []int32{0, 1, 2}, MinISRReplicas: 1},
+				{Topic: "orders-dlq", Partition: 0, Replicas: []int32{3}},
+			},
+			UnderReplicatedPartitions: []types.PartitionState{
+				{Topic: "payments", Partition: 1, Replicas: []int32{0, 3}, MinISRReplicas: 1},
+			},
+		},
+	}
+}
+
+func testLoad() *types.PartitionLoadState {
+	return &types.PartitionLoadState{Records: []types.PartitionLoad{
+		{Topic: "orders", Partition: 0, Disk: 100},
+		{Topic: "orders", Partition: 1, Disk: 200},
+		{Topic: "payments", Partition: 0, Disk: 60},
+		{Topic: "payments", Partition: 1, Disk: 40},
+	}}
+}
+
+func TestPreview(t *testing.T) {
+	t.Run("Increase replication factor", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan, err := Preview(testState(), testLoad(), "orders|payments", 3)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.Brokers).To(Equal([]int32{0, 1, 2, 3}))
+		g.Expect(plan.Topics).To(HaveLen(2))
+		g.Expect(plan.Feasible()).To(BeTrue())
+
+		orders := plan.Topics[0]
+		g.Expect(orders.Topic).To(Equal("orders"))
+		g.Expect(orders.Partitions).To(Equal(2))
+		g.Expect(orders.MinCurrentRF).To(Equal(int32(2)))
+		g.Expect(orders.MaxCurrentRF).To(Equal(int32(2)))
+		g.Expect(orders.MinISR).To(Equal(int32(2)))
+		g.Expect(orders.ExtraDiskMB).To(BeNumerically("~", 300))
+
+		payments := plan.Topics[1]
+		g.Expect(payments.MinCurrentRF).To(Equal(int32(2)))
+		g.Expect(payments.MaxCurrentRF).To(Equal(int32(3)))
+		g.Expect(payments.ExtraDiskMB).To(BeNumerically("~", 40))
+
+		g.Expect(plan.ExtraDiskMB()).To(BeNumerically("~", 340))
+		// orders-0 goes to 2 or 3, orders-1 to 0 or 3 and payments-1 to 1 or 2.
+		g.Expect(plan.ExtraDiskMBByBroker[0]).To(BeNumerically("~", 100))
+		g.Expect(plan.ExtraDiskMBByBroker[1]).To(BeNumerically("~", 20))
+		g.Expect(plan.ExtraDiskMBByBroker[2]).To(BeNumerically("~", 70))
+		g.Expect(plan.ExtraDiskMBByBroker[3]).To(BeNumerically("~", 150))
+		g.Expect(plan.Changed()).To(Equal([]string{"orders", "payments"}))
+	})
+
+	t.Run("Unsatisfiable constraints", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan, err := Preview(testState(), testLoad(), "orders", 1)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.Feasible()).To(BeFalse())
+		g.Expect(plan.Topics[0].Problems).To(HaveLen(1))
+		g.Expect(plan.Topics[0].ExtraDiskMB).To(BeNumerically("~", -300))
+
+		plan, err = Preview(testState(), testLoad(), "payments", 5)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.Feasible()).To(BeFalse())
+	})
+
+	t.Run("Partition in multiple lists", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := testState()
+		urp := state.KafkaPartitionState.UnderReplicatedPartitions[0]
+		state.KafkaPartitionState.UnderMinISR = []types.PartitionState{urp}
+		state.KafkaPartitionState.WithOfflineReplicas = []types.PartitionState{urp}
+
+		plan, err := Preview(state, testLoad(), "payments", 3)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.Topics).To(HaveLen(1))
+		g.Expect(plan.Topics[0].Partitions).To(Equal(2))
+		g.Expect(plan.Topics[0].SizeMB).To(BeNumerically("~", 100))
+		g.Expect(plan.Topics[0].ExtraDiskMB).To(BeNumerically("~", 40))
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := Preview(testState(), nil, "(", 3)
+		g.Expect(err).To(HaveOccurred())
+		_, err = Preview(testState(), nil, "orders", 0)
+		g.Expect(err).To(HaveOccurred())
+		_, err = Preview(nil, nil, "orders", 3)
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestNewPlan(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Plan", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan, err := NewPlan(ctx, &fakeClient{state: testState(), load: testLoad()}, "orders", 3)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(plan.Changed()).To(Equal([]string{"orders"}))
+	})
+
+	t.Run("Missing results", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := NewPlan(ctx, &fakeClient{state: testState()}, "orders", 3)
+		g.Expect(err).To(MatchError(ContainSubstring("partition load is missing")))
+		_, err = NewPlan(ctx, &fakeClient{load: testLoad()}, "orders", 3)
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestBatches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	plan := &Plan{TargetRF: 3, Topics: []TopicChange{
+		{Topic: "a", MinCurrentRF: 2, MaxCurrentRF: 2, TargetRF: 3},
+		{Topic: "b", MinCurrentRF: 3, MaxCurrentRF: 3, TargetRF: 3},
+		{Topic: "c.d", MinCurrentRF: 2, MaxCurrentRF: 3, TargetRF: 3},
+		{Topic: "e", MinCurrentRF: 1, MaxCurrentRF: 1, TargetRF: 3},
+	}}
+	batches := plan.Batches(2)
+	g.Expect(batches).To(Equal([][]string{{"a", "c.d"}, {"e"}}))
+
+	re := regexp.MustCompile("^(?:" + TopicsPattern(batches[0]) + ")$")
+	g.Expect(re.MatchString("c.d")).To(BeTrue())
+	g.Expect(re.MatchString("cxd")).To(BeFalse())
+	g.Expect(re.MatchString("a")).To(BeTrue())
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicationfactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const DefaultBatchSize = 10
+
+// ErrInfeasiblePlan is returned when running a plan whose topics have problems.
+var ErrInfeasiblePlan = errors.New("replication factor change plan is not feasible")
+
+// RunClient is implemented by clients which are able to change the replication factor of topics and to watch
+// and stop the triggered executions.
+type RunClient interface {
+	client.ExecutionClient
+	TopicConfiguration(ctx context.Context, r *api.TopicConfigurationRequest) (*api.TopicConfigurationResponse, error)
+}
+
+// RunConfig contains the configuration parameters for running a plan.
+type RunConfig struct {
+	// BatchSize is the number of topics changed by a single request. DefaultBatchSize is used if not set.
+	BatchSize int
+	// Request is used as a template for the request of each batch. Its Topic, ReplicationFactor and DryRun fields
+	// are overridden. api.TopicConfigurationRequestWithDefaults is used if not set.
+	Request *api.TopicConfigurationRequest
+	// Execution contains the options for waiting for the execution of each batch.
+	Execution *client.ExecutionOptions
+	// Force runs the plan even if it is not feasible.
+	Force bool
+}
+
+func (c RunConfig) withDefaults() RunConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.Request == nil {
+		c.Request = api.TopicConfigurationRequestWithDefaults()
+	}
+	return c
+}
+
+// BatchResult is the outcome of changing the replication factor of a batch of topics.
+type BatchResult struct {
+	Topics     []string             `json:"topics"`
+	UserTaskID string               `json:"userTaskID,omitempty"`
+	Status     types.UserTaskStatus `json:"status"`
+	Start      time.Time            `json:"start"`
+	End        time.Time            `json:"end"`
+	// Data moved and the number of replica movements of the batch.
+	DataToMoveMB        int64  `json:"dataToMoveMB"`
+	NumReplicaMovements int32  `json:"numReplicaMovements"`
+	Error               string `json:"error,omitempty"`
+}
+
+// Batches splits the topics whose replication factor needs to be changed into batches of the provided size.
+func (p *Plan) Batches(size int) [][]string {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	topics := p.Changed()
+	batches := make([][]string, 0, (len(topics)+size-1)/size)
+	for start := 0; start < len(topics); start += size {
+		batches = append(batches, topics[start:min(start+size, len(topics))])
+	}
+	return batches
+}
+
+// Run changes the replication factor of the topics of the plan batch by batch, waiting for the execution of each
+// batch to finish before starting the next one. It stops at the first failed batch and returns the results of
+// the batches run so far. If the context is cancelled, the execution of the current batch is stopped.
+func Run(ctx context.Context, c RunClient, plan *Plan, config RunConfig) ([]BatchResult, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	config = config.withDefaults()
+
+	if !plan.Feasible() && !config.Force {
+		return nil, ErrInfeasiblePlan
+	}
+
+	batches := plan.Batches(config.BatchSize)
+	results := make([]BatchResult, 0, len(batches))
+	for n, topics := range batches {
+		log.V(0).Info("changing replication factor of topics", "batch", n+1, "batches", len(batches),
+			"topics", topics, "replication_factor", plan.TargetRF)
+
+		result := runBatch(ctx, c, plan.TargetRF, topics, config)
+		results = append(results, result)
+		if result.Error != "" {
+			return results, fmt.Errorf("failed to change replication factor of batch %d of %d: %s",
+				n+1, len(batches), result.Error)
+		}
+	}
+	return results, nil
+}
+
+func runBatch(ctx context.Context, c RunClient, targetRF int32, topics []string, config RunConfig) BatchResult {
+	result := BatchResult{Topics: topics, Start: time.Now()}
+
+	req := *config.Request
+	req.Topic = TopicsPattern(topics)
+	req.ReplicationFactor = targetRF
+	req.DryRun = false
+	if req.Reason == "" {
+		req.Reason = fmt.Sprintf("change replication factor of %d topics to %d", len(topics), targetRF)
+	}
+
+	resp, report, err := client.Execute(ctx, c, c.TopicConfiguration, &req, config.Execution)
+	result.End = time.Now()
+	if report != nil {
+		result.UserTaskID = report.UserTaskID
+		result.Status = report.Status
+	}
+	if resp != nil && resp.Result != nil {
+		result.DataToMoveMB = resp.Result.Summary.DataToMoveMB
+		result.NumReplicaMovements = resp.Result.Summary.NumReplicaMovements
+	}
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+	case result.Status == types.UserTaskStatusCompletedWithError:
+		result.Error = fmt.Sprintf("user task %s completed with error", result.UserTaskID)
+	}
+	return result
+}
+
+// TopicsPattern returns the pattern matching exactly the provided topics.
+func TopicsPattern(topics []string) string {
+	quoted := make([]string, len(topics))
+	for i, t := range topics {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	return strings.Join(quoted, "|")
+}
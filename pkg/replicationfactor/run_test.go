@@ -0,0 +1,162 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicationfactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// fakeRunClient finishes the user task of each request immediately with the status configured for the request.
+type fakeRunClient struct {
+	statuses []types.UserTaskStatus
+	err      error
+
+	requests []api.TopicConfigurationRequest
+}
+
+func (f *fakeRunClient) TopicConfiguration(_ context.Context, r *api.TopicConfigurationRequest) (*api.TopicConfigurationResponse, error) { //nolint:lll
+	f.requests = append(f.requests, *r)
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := &api.TopicConfigurationResponse{
+		Result: &types.OptimizationResult{Summary: types.OptimizerResult{
+			DataToMoveMB:        int64(100 * len(f.requests)),
+			NumReplicaMovements: int32(len(f.requests)),
+		}},
+	}
+	resp.TaskID = fmt.Sprintf("task-%d", len(f.requests))
+	return resp, nil
+}
+
+func (f *fakeRunClient) UserTasks(_ context.Context, r *api.UserTasksRequest) (*api.UserTasksResponse, error) {
+	var n int
+	if _, err := fmt.Sscanf(r.UserTaskIDs[0], "task-%d", &n); err != nil {
+		return nil, err
+	}
+	status := types.UserTaskStatusCompleted
+	if n <= len(f.statuses) {
+		status = f.statuses[n-1]
+	}
+	return &api.UserTasksResponse{Result: &types.UserTaskState{
+		UserTasks: []types.UserTaskInfo{{UserTaskID: r.UserTaskIDs[0], Status: status}},
+	}}, nil
+}
+
+func (f *fakeRunClient) State(_ context.Context, _ *api.StateRequest) (*api.StateResponse, error) {
+	state := types.StateResult{}
+	state.ExecutorState.State = types.ExecutorStateTypeNoTaskInProgress
+	return &api.StateResponse{Result: &state}, nil
+}
+
+func (f *fakeRunClient) StopProposalExecution(_ context.Context, _ *api.StopProposalExecutionRequest) (*api.StopProposalExecutionResponse, error) { //nolint:lll
+	return &api.StopProposalExecutionResponse{}, nil
+}
+
+func testPlan() *Plan {
+	return &Plan{TargetRF: 3, Topics: []TopicChange{
+		{Topic: "a", MinCurrentRF: 2, MaxCurrentRF: 2, TargetRF: 3},
+		{Topic: "b", MinCurrentRF: 2, MaxCurrentRF: 2, TargetRF: 3},
+		{Topic: "c", MinCurrentRF: 2, MaxCurrentRF: 2, TargetRF: 3},
+	}}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	config := RunConfig{BatchSize: 2, Execution: &client.ExecutionOptions{PollInterval: 10 * time.Millisecond}}
+
+	t.Run("Batches", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := &fakeRunClient{}
+		results, err := Run(ctx, c, testPlan(), config)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(c.requests).To(HaveLen(2))
+		g.Expect(c.requests[0].Topic).To(Equal("a|b"))
+		g.Expect(c.requests[1].Topic).To(Equal("c"))
+		for _, req := range c.requests {
+			g.Expect(req.ReplicationFactor).To(Equal(int32(3)))
+			g.Expect(req.DryRun).To(BeFalse())
+			g.Expect(req.Reason).NotTo(BeEmpty())
+		}
+
+		g.Expect(results).To(HaveLen(2))
+		g.Expect(results[0].Topics).To(Equal([]string{"a", "b"}))
+		g.Expect(results[0].UserTaskID).To(Equal("task-1"))
+		g.Expect(results[0].Status).To(Equal(types.UserTaskStatusCompleted))
+		g.Expect(results[0].DataToMoveMB).To(Equal(int64(100)))
+		g.Expect(results[1].NumReplicaMovements).To(Equal(int32(2)))
+		g.Expect(results[1].Error).To(BeEmpty())
+		for _, result := range results {
+			g.Expect(result.Start.IsZero()).To(BeFalse())
+			g.Expect(result.End.Before(result.Start)).To(BeFalse())
+		}
+		g.Expect(results[1].Start.Before(results[0].End)).To(BeFalse())
+	})
+
+	t.Run("Stops at the first failed batch", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := &fakeRunClient{statuses: []types.UserTaskStatus{types.UserTaskStatusCompletedWithError}}
+		results, err := Run(ctx, c, testPlan(), config)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(c.requests).To(HaveLen(1))
+		g.Expect(results).To(HaveLen(1))
+		g.Expect(results[0].Status).To(Equal(types.UserTaskStatusCompletedWithError))
+		g.Expect(results[0].Error).To(ContainSubstring("task-1"))
+	})
+
+	t.Run("Failed request", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := &fakeRunClient{err: errors.New("boom")}
+		results, err := Run(ctx, c, testPlan(), config)
+		g.Expect(err).To(MatchError(ContainSubstring("boom")))
+		g.Expect(results).To(HaveLen(1))
+		g.Expect(results[0].UserTaskID).To(BeEmpty())
+		g.Expect(results[0].End.IsZero()).To(BeFalse())
+	})
+
+	t.Run("Infeasible plan", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		plan := testPlan()
+		plan.Topics[0].Problems = []string{"replication factor 3 exceeds the number of brokers 2"}
+
+		c := &fakeRunClient{}
+		_, err := Run(ctx, c, plan, config)
+		g.Expect(errors.Is(err, ErrInfeasiblePlan)).To(BeTrue())
+		g.Expect(c.requests).To(BeEmpty())
+
+		forced := config
+		forced.Force = true
+		_, err = Run(ctx, c, plan, forced)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c.requests).To(HaveLen(2))
+	})
+}
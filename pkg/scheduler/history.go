@@ -0,0 +1,109 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeSkippedOutsideWindow    Outcome = "skipped-outside-window"
+	OutcomeSkippedOngoingExecution Outcome = "skipped-ongoing-execution"
+	OutcomeSkippedNotReady         Outcome = "skipped-not-ready"
+	OutcomeSkippedBalanced         Outcome = "skipped-balanced"
+	OutcomeSkippedLowImprovement   Outcome = "skipped-low-improvement"
+	OutcomeSkippedTooMuchData      Outcome = "skipped-too-much-data"
+	OutcomeExecuted                Outcome = "executed"
+	OutcomeStopped                 Outcome = "stopped"
+	OutcomeFailed                  Outcome = "failed"
+)
+
+// Outcome of a scheduled run.
+type Outcome string
+
+// Skipped returns true if the run did not execute a rebalance.
+func (o Outcome) Skipped() bool {
+	switch o {
+	case OutcomeExecuted, OutcomeStopped, OutcomeFailed:
+		return false
+	default:
+		return true
+	}
+}
+
+// RunRecord describes a scheduled run.
+type RunRecord struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Outcome Outcome   `json:"outcome"`
+	// Reason describes why the run ended with its outcome in human-readable form.
+	Reason string `json:"reason,omitempty"`
+	// BalancednessScore reported by the anomaly detector at the beginning of the run.
+	BalancednessScore float64 `json:"balancednessScore"`
+	// Balancedness scores before and after the rebalance according to the dry-run.
+	ScoreBefore float64 `json:"scoreBefore,omitempty"`
+	ScoreAfter  float64 `json:"scoreAfter,omitempty"`
+	// Data to move and the number of movements according to the dry-run.
+	DataToMoveMB        int64 `json:"dataToMoveMB,omitempty"`
+	NumReplicaMovements int32 `json:"numReplicaMovements,omitempty"`
+	NumLeaderMovements  int32 `json:"numLeaderMovements,omitempty"`
+	// UserTaskID of the executed rebalance.
+	UserTaskID string `json:"userTaskID,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Duration returns the time the run took.
+func (r RunRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// HistoryStore stores the records of the scheduled runs.
+type HistoryStore interface {
+	Record(ctx context.Context, r RunRecord) error
+	// List returns the stored records in the order they were recorded.
+	List(ctx context.Context) ([]RunRecord, error)
+}
+
+// MemoryHistory is a HistoryStore keeping the records in memory. The zero value is ready to use.
+type MemoryHistory struct {
+	// Limit is the maximum number of records kept. The oldest records are dropped if it is exceeded.
+	// All records are kept if not set.
+	Limit int
+
+	mu      sync.Mutex
+	records []RunRecord
+}
+
+func (h *MemoryHistory) Record(_ context.Context, r RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records = append(h.records, r)
+	if h.Limit > 0 && len(h.records) > h.Limit {
+		h.records = append([]RunRecord(nil), h.records[len(h.records)-h.Limit:]...)
+	}
+	return nil
+}
+
+func (h *MemoryHistory) List(_ context.Context) ([]RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]RunRecord(nil), h.records...), nil
+}
@@ -0,0 +1,334 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultDryRunPollInterval = 5 * time.Second
+	DefaultReason             = "scheduled rebalance"
+)
+
+// Client is the subset of the Cruise Control client API used by the Runner.
+type Client interface {
+	client.ExecutionClient
+	Rebalance(ctx context.Context, r *api.RebalanceRequest) (*api.RebalanceResponse, error)
+}
+
+// Config contains the configuration parameters of the scheduled rebalance runner.
+type Config struct {
+	// Schedule defines when rebalance runs are started.
+	Schedule *Schedule
+	// Windows in which rebalances are allowed to run. Runs are allowed at any time if empty. Runs are skipped if
+	// their window closes before the dry-run finishes and executions still running when it closes are stopped.
+	Windows []Window
+	// TargetBalancednessScore is the balancedness score reported by the anomaly detector at or above which
+	// the cluster is considered balanced and the run is skipped. Runs are never skipped based on the reported
+	// score if not set.
+	TargetBalancednessScore float64
+	// MinBalancednessImprovement is the minimum difference between the balancedness score after and before
+	// the rebalance according to the dry-run which is required for executing it.
+	MinBalancednessImprovement float64
+	// MaxDataToMoveMB is the maximum amount of data the rebalance is allowed to move. Not limited if not set.
+	MaxDataToMoveMB int64
+	// Request is used as a template for the dry-run and the executed rebalance request. The value of DryRun is
+	// ignored. api.RebalanceRequestWithDefaults is used with DefaultReason as reason if not set.
+	Request *api.RebalanceRequest
+	// History stores the record of each run. Records are kept in memory if not set.
+	History HistoryStore
+	// Execution contains the options for running the rebalance.
+	Execution *client.ExecutionOptions
+	// DryRunPollInterval is the interval between checking whether the dry-run has finished.
+	// DefaultDryRunPollInterval is used if not set.
+	DryRunPollInterval time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Request == nil {
+		c.Request = api.RebalanceRequestWithDefaults()
+		c.Request.Reason = DefaultReason
+	}
+	if c.History == nil {
+		c.History = &MemoryHistory{}
+	}
+	if c.DryRunPollInterval <= 0 {
+		c.DryRunPollInterval = DefaultDryRunPollInterval
+	}
+	return c
+}
+
+// Runner runs rebalances on a schedule within maintenance windows if the dry-run of the rebalance shows
+// that it is worth executing.
+type Runner struct {
+	client Client
+	config Config
+}
+
+// NewRunner returns a new Runner using the provided client and configuration. It returns an error if
+// the schedule is missing.
+func NewRunner(c Client, config Config) (*Runner, error) {
+	if config.Schedule == nil {
+		return nil, errors.New("schedule must be set for scheduled rebalances")
+	}
+	return &Runner{
+		client: c,
+		config: config.withDefaults(),
+	}, nil
+}
+
+// History returns the store holding the records of the runs.
+func (r *Runner) History() HistoryStore {
+	return r.config.History
+}
+
+// Run starts a run each time the schedule fires until the context is cancelled. Runs do not overlap: if a run
+// takes longer than the time until the next scheduled one, the missed runs are skipped.
+func (r *Runner) Run(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for {
+		next := r.config.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("schedule does not fire within the next five years")
+		}
+		log.V(0).Info("next scheduled rebalance", "time", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		record := r.RunOnce(ctx)
+		log.V(0).Info("scheduled rebalance finished", "outcome", record.Outcome, "reason", record.Reason)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// RunOnce checks the state of Cruise Control, runs the rebalance in dry-run mode and executes it if it meets
+// the configured thresholds. The record of the run is returned and stored in the history.
+func (r *Runner) RunOnce(ctx context.Context) RunRecord {
+	log := logr.FromContextOrDiscard(ctx)
+
+	record := RunRecord{Start: time.Now()}
+	r.run(ctx, &record)
+	record.End = time.Now()
+
+	if err := r.config.History.Record(ctx, record); err != nil {
+		log.Error(err, "failed to record scheduled rebalance")
+	}
+	return record
+}
+
+func (r *Runner) run(ctx context.Context, record *RunRecord) {
+	closeAt, ok := r.windowClose(record.Start)
+	if !ok {
+		record.Outcome = OutcomeSkippedOutsideWindow
+		record.Reason = "no maintenance window is open"
+		return
+	}
+
+	state, err := r.client.State(ctx, api.StateRequestWithDefaults())
+	if err != nil {
+		record.fail(fmt.Errorf("failed to get Cruise Control state: %w", err))
+		return
+	}
+	if skipped := r.checkState(state.Result, record); skipped {
+		return
+	}
+
+	// The rebalance is only executed while the maintenance window is open, so the dry-run is bound by it too.
+	dryRunCtx := ctx
+	if !closeAt.IsZero() {
+		var cancel context.CancelFunc
+		dryRunCtx, cancel = context.WithDeadline(ctx, closeAt)
+		defer cancel()
+	}
+	summary, err := r.dryRun(dryRunCtx)
+	if err != nil {
+		if errors.Is(dryRunCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			record.Outcome = OutcomeSkippedOutsideWindow
+			record.Reason = "maintenance window closed during the dry-run"
+			return
+		}
+		record.fail(err)
+		return
+	}
+	record.ScoreBefore = summary.OnDemandBalancednessScoreBefore
+	record.ScoreAfter = summary.OnDemandBalancednessScoreAfter
+	record.DataToMoveMB = summary.DataToMoveMB
+	record.NumReplicaMovements = summary.NumReplicaMovements
+	record.NumLeaderMovements = summary.NumLeaderMovements
+	if skipped := r.checkThresholds(summary, record); skipped {
+		return
+	}
+
+	if closeAt, ok = r.windowClose(time.Now()); !ok {
+		record.Outcome = OutcomeSkippedOutsideWindow
+		record.Reason = "maintenance window closed before the execution"
+		return
+	}
+
+	req := *r.config.Request
+	req.DryRun = false
+
+	execCtx := ctx
+	if !closeAt.IsZero() {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithDeadline(ctx, closeAt)
+		defer cancel()
+	}
+	_, report, err := client.Execute(execCtx, r.client, r.client.Rebalance, &req, r.config.Execution)
+	if report != nil {
+		record.UserTaskID = report.UserTaskID
+	}
+	switch {
+	case report != nil && report.Aborted:
+		record.Outcome = OutcomeStopped
+		record.Reason = "execution was stopped"
+		if errors.Is(execCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			record.Reason = "execution was stopped as the maintenance window closed"
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+	case err != nil:
+		record.fail(fmt.Errorf("failed to execute rebalance: %w", err))
+	case report.Status == types.UserTaskStatusCompletedWithError:
+		record.Outcome = OutcomeFailed
+		record.Reason = fmt.Sprintf("user task finished with status %s", report.Status)
+	default:
+		record.Outcome = OutcomeExecuted
+		record.Reason = fmt.Sprintf("user task finished with status %s", report.Status)
+	}
+}
+
+// windowClose returns the closing time of the latest closing window containing t and whether any window
+// contains it. The zero time is returned if there are no windows.
+func (r *Runner) windowClose(t time.Time) (time.Time, bool) {
+	if len(r.config.Windows) == 0 {
+		return time.Time{}, true
+	}
+
+	var closeAt time.Time
+	var open bool
+	for _, w := range r.config.Windows {
+		if c, ok := w.Close(t); ok {
+			open = true
+			if c.After(closeAt) {
+				closeAt = c
+			}
+		}
+	}
+	return closeAt, open
+}
+
+func (r *Runner) checkState(state *types.StateResult, record *RunRecord) bool {
+	if state == nil {
+		record.fail(errors.New("Cruise Control state is missing from the response"))
+		return true
+	}
+	record.BalancednessScore = state.AnomalyDetectorState.BalancednessScore
+
+	switch {
+	case state.ExecutorState.State != types.ExecutorStateTypeNoTaskInProgress:
+		record.Outcome = OutcomeSkippedOngoingExecution
+		record.Reason = fmt.Sprintf("executor is in %s state", state.ExecutorState.State)
+	case !state.AnalyzerState.IsProposalReady:
+		record.Outcome = OutcomeSkippedNotReady
+		record.Reason = "proposals are not ready"
+	case r.config.TargetBalancednessScore > 0 && record.BalancednessScore >= r.config.TargetBalancednessScore:
+		record.Outcome = OutcomeSkippedBalanced
+		record.Reason = fmt.Sprintf("balancedness score %.2f reached the target %.2f",
+			record.BalancednessScore, r.config.TargetBalancednessScore)
+	default:
+		return false
+	}
+	return true
+}
+
+func (r *Runner) checkThresholds(summary *types.OptimizerResult, record *RunRecord) bool {
+	improvement := summary.OnDemandBalancednessScoreAfter - summary.OnDemandBalancednessScoreBefore
+
+	switch {
+	case summary.NumReplicaMovements == 0 && summary.NumLeaderMovements == 0 && summary.NumIntraBrokerReplicaMovements == 0:
+		record.Outcome = OutcomeSkippedBalanced
+		record.Reason = "rebalance does not propose any movements"
+	case improvement < r.config.MinBalancednessImprovement:
+		record.Outcome = OutcomeSkippedLowImprovement
+		record.Reason = fmt.Sprintf("balancedness improvement %.2f is below %.2f",
+			improvement, r.config.MinBalancednessImprovement)
+	case r.config.MaxDataToMoveMB > 0 && summary.DataToMoveMB > r.config.MaxDataToMoveMB:
+		record.Outcome = OutcomeSkippedTooMuchData
+		record.Reason = fmt.Sprintf("data to move %d MB exceeds %d MB", summary.DataToMoveMB, r.config.MaxDataToMoveMB)
+	default:
+		return false
+	}
+	return true
+}
+
+// dryRun runs the rebalance in dry-run mode and waits for its result. The requests are sent in the same
+// session so Cruise Control returns the progress of the same user task until it finishes.
+func (r *Runner) dryRun(ctx context.Context) (*types.OptimizerResult, error) {
+	ctx = client.ContextWithSession(ctx)
+
+	req := *r.config.Request
+	req.DryRun = true
+
+	ticker := time.NewTicker(r.config.DryRunPollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := r.client.Rebalance(ctx, &req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run rebalance in dry-run mode: %w", err)
+		}
+		if !resp.InProgress() {
+			if resp.Result == nil {
+				return nil, errors.New("result is missing from the response of the rebalance dry-run")
+			}
+			return &resp.Result.Summary, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *RunRecord) fail(err error) {
+	r.Outcome = OutcomeFailed
+	r.Reason = "run failed"
+	r.Error = err.Error()
+}
@@ -0,0 +1,276 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/client"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const testUserTaskID = "task-1"
+
+// fakeClient returns in progress responses for the first pending dry-runs. The executed rebalance keeps running
+// with the configured status until it is stopped.
+type fakeClient struct {
+	mu      sync.Mutex
+	state   types.StateResult
+	summary types.OptimizerResult
+	pending int
+	status  types.UserTaskStatus
+
+	dryRuns    int
+	executions int
+	stopped    bool
+}
+
+func (f *fakeClient) State(_ context.Context, _ *api.StateRequest) (*api.StateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state := f.state
+	if f.executions > 0 && !f.stopped {
+		state.ExecutorState = types.ExecutorState{
+			TriggeredUserTaskID: testUserTaskID,
+			State:               types.ExecutorStateTypeInterBrokerReplicaMovementTaskInProgress,
+		}
+	}
+	return &api.StateResponse{Result: &state}, nil
+}
+
+func (f *fakeClient) Rebalance(_ context.Context, r *api.RebalanceRequest) (*api.RebalanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resp := &api.RebalanceResponse{}
+	if r.DryRun {
+		f.dryRuns++
+		if f.dryRuns <= f.pending {
+			resp.Progress = &types.ProgressResult{}
+			return resp, nil
+		}
+	} else {
+		f.executions++
+		resp.TaskID = testUserTaskID
+	}
+	resp.Result = &types.OptimizationResult{Summary: f.summary}
+	return resp, nil
+}
+
+func (f *fakeClient) UserTasks(_ context.Context, _ *api.UserTasksRequest) (*api.UserTasksResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := f.status
+	if f.stopped {
+		status = types.UserTaskStatusCompleted
+	}
+	return &api.UserTasksResponse{Result: &types.UserTaskState{
+		UserTasks: []types.UserTaskInfo{{UserTaskID: testUserTaskID, Status: status}},
+	}}, nil
+}
+
+func (f *fakeClient) StopProposalExecution(_ context.Context, _ *api.StopProposalExecutionRequest) (*api.StopProposalExecutionResponse, error) { //nolint:lll
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	return &api.StopProposalExecutionResponse{}, nil
+}
+
+func readyState(score float64) types.StateResult {
+	state := types.StateResult{}
+	state.ExecutorState.State = types.ExecutorStateTypeNoTaskInProgress
+	state.AnalyzerState.IsProposalReady = true
+	state.AnomalyDetectorState.BalancednessScore = score
+	return state
+}
+
+func newTestRunner(t *testing.T, c Client, config Config) *Runner {
+	t.Helper()
+
+	config.Schedule = &Schedule{}
+	config.DryRunPollInterval = 10 * time.Millisecond
+	config.Execution = &client.ExecutionOptions{PollInterval: 10 * time.Millisecond, StopTimeout: time.Second}
+	r, err := NewRunner(c, config)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+	return r
+}
+
+func TestNewRunner(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, err := NewRunner(&fakeClient{}, Config{})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRunOnce(t *testing.T) {
+	ctx := context.Background()
+	improving := types.OptimizerResult{
+		NumReplicaMovements:             10,
+		DataToMoveMB:                    1000,
+		OnDemandBalancednessScoreBefore: 70,
+		OnDemandBalancednessScoreAfter:  90,
+	}
+
+	tests := []struct {
+		name       string
+		state      types.StateResult
+		summary    types.OptimizerResult
+		config     Config
+		outcome    Outcome
+		dryRuns    int
+		executions int
+	}{
+		{
+			name:    "Ongoing execution",
+			state:   types.StateResult{ExecutorState: types.ExecutorState{State: types.ExecutorStateTypeLeaderMovementTaskInProgress}},
+			outcome: OutcomeSkippedOngoingExecution,
+		},
+		{
+			name:    "Proposals not ready",
+			state:   types.StateResult{ExecutorState: types.ExecutorState{State: types.ExecutorStateTypeNoTaskInProgress}},
+			outcome: OutcomeSkippedNotReady,
+		},
+		{
+			name:    "Target balancedness reached",
+			state:   readyState(95),
+			config:  Config{TargetBalancednessScore: 90},
+			outcome: OutcomeSkippedBalanced,
+		},
+		{
+			name:    "No movements proposed",
+			state:   readyState(80),
+			outcome: OutcomeSkippedBalanced,
+			dryRuns: 3,
+		},
+		{
+			name:    "Improvement below threshold",
+			state:   readyState(80),
+			summary: improving,
+			config:  Config{MinBalancednessImprovement: 25},
+			outcome: OutcomeSkippedLowImprovement,
+			dryRuns: 3,
+		},
+		{
+			name:    "Too much data to move",
+			state:   readyState(80),
+			summary: improving,
+			config:  Config{MaxDataToMoveMB: 500},
+			outcome: OutcomeSkippedTooMuchData,
+			dryRuns: 3,
+		},
+		{
+			name:       "Executed",
+			state:      readyState(80),
+			summary:    improving,
+			config:     Config{TargetBalancednessScore: 90, MinBalancednessImprovement: 20, MaxDataToMoveMB: 1000},
+			outcome:    OutcomeExecuted,
+			dryRuns:    3,
+			executions: 1,
+		},
+		{
+			name:    "Outside of windows",
+			state:   readyState(80),
+			summary: improving,
+			config: Config{Windows: []Window{{
+				Start:    time.Hour,
+				End:      2 * time.Hour,
+				Weekdays: []time.Weekday{time.Now().Add(-48 * time.Hour).Weekday()},
+			}}},
+			outcome: OutcomeSkippedOutsideWindow,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			c := &fakeClient{state: test.state, summary: test.summary, pending: 2, status: types.UserTaskStatusCompleted}
+			r := newTestRunner(t, c, test.config)
+
+			record := r.RunOnce(ctx)
+			g.Expect(record.Outcome).To(Equal(test.outcome), record.Reason)
+			g.Expect(c.dryRuns).To(Equal(test.dryRuns))
+			g.Expect(c.executions).To(Equal(test.executions))
+
+			records, err := r.History().List(ctx)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(records).To(Equal([]RunRecord{record}))
+		})
+	}
+
+	t.Run("Execution completed with error", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		c := &fakeClient{state: readyState(80), summary: improving, status: types.UserTaskStatusCompletedWithError}
+		r := newTestRunner(t, c, Config{})
+
+		record := r.RunOnce(ctx)
+		g.Expect(record.Outcome).To(Equal(OutcomeFailed))
+		g.Expect(record.UserTaskID).To(Equal(testUserTaskID))
+	})
+
+	t.Run("Execution is stopped when the window closes", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		now := time.Now().UTC()
+		sinceMidnight := now.Sub(now.Truncate(24 * time.Hour))
+		window := Window{
+			Start:    max(0, sinceMidnight-time.Minute),
+			End:      sinceMidnight + 200*time.Millisecond,
+			Location: time.UTC,
+		}
+		c := &fakeClient{state: readyState(80), summary: improving, status: types.UserTaskStatusInExecution}
+		r := newTestRunner(t, c, Config{Windows: []Window{window}})
+
+		record := r.RunOnce(ctx)
+		g.Expect(record.Outcome).To(Equal(OutcomeStopped))
+		g.Expect(record.Reason).To(ContainSubstring("maintenance window closed"))
+		g.Expect(c.stopped).To(BeTrue())
+	})
+
+	t.Run("Window closes during the dry-run", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		now := time.Now().UTC()
+		sinceMidnight := now.Sub(now.Truncate(24 * time.Hour))
+		window := Window{
+			Start:    max(0, sinceMidnight-time.Minute),
+			End:      sinceMidnight + 50*time.Millisecond,
+			Location: time.UTC,
+		}
+		c := &fakeClient{state: readyState(80), summary: improving, pending: 100, status: types.UserTaskStatusCompleted}
+		r := newTestRunner(t, c, Config{Windows: []Window{window}})
+
+		record := r.RunOnce(ctx)
+		g.Expect(record.Outcome).To(Equal(OutcomeSkippedOutsideWindow), record.Reason)
+		g.Expect(record.Reason).To(ContainSubstring("maintenance window closed"))
+		g.Expect(record.End.Sub(record.Start)).To(BeNumerically("<", time.Second))
+		g.Expect(c.executions).To(BeZero())
+	})
+}
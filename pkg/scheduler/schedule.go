@@ -0,0 +1,266 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var scheduleDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Schedule is a cron schedule with minute, hour, day of month, month and day of week fields.
+type Schedule struct {
+	// Location is the time zone the schedule is evaluated in. Local time is used if not set.
+	Location *time.Location
+
+	minute, hour, dom, month, dow []bool
+	// domAny and dowAny are true if the day of month or the day of week field is a wildcard.
+	domAny, dowAny bool
+}
+
+// ParseSchedule parses a standard cron expression with five fields, e.g. "0 2 * * 1-5". Fields support
+// wildcards, lists, ranges and steps. The @hourly, @daily, @midnight, @weekly and @monthly descriptors are
+// supported as well.
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := scheduleDescriptors[expr]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 { //nolint:gomnd
+		return nil, errors.Errorf("invalid schedule %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("invalid minute field of schedule %q: %w", expr, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("invalid hour field of schedule %q: %w", expr, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("invalid day of month field of schedule %q: %w", expr, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("invalid month field of schedule %q: %w", expr, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("invalid day of week field of schedule %q: %w", expr, err)
+	}
+	// Both 0 and 7 mean Sunday.
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+// MustParseSchedule is like ParseSchedule but panics if the expression cannot be parsed.
+func MustParseSchedule(expr string) *Schedule {
+	s, err := ParseSchedule(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, errors.Errorf("invalid step in %q", part)
+			}
+			rng = part[:i]
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2) //nolint:gomnd
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, errors.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 { //nolint:gomnd
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, errors.Errorf("invalid value in %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, errors.Errorf("value out of range [%d, %d] in %q", min, max, part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *Schedule) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+	return s.Location
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom[t.Day()]
+	dow := s.dow[int(t.Weekday())]
+	// Like cron, if both the day of month and the day of week are restricted, either of them needs to match.
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first time matching the schedule after t. It returns the zero time if there is no such time
+// within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := s.location()
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0) //nolint:gomnd
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !s.month[int(m)]:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case !s.hour[t.Hour()]:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Window is a daily time window in which scheduled runs are allowed. Windows ending before they start span
+// midnight, e.g. a window from 22:00 to 04:00 ends on the next day.
+type Window struct {
+	// Start of the window as the time elapsed since midnight.
+	Start time.Duration
+	// End of the window as the time elapsed since midnight.
+	End time.Duration
+	// Weekdays the window starts on. Every day is allowed if empty.
+	Weekdays []time.Weekday
+	// Location is the time zone the window is evaluated in. Local time is used if not set.
+	Location *time.Location
+}
+
+// ParseWindow parses a window in "HH:MM-HH:MM" format.
+func ParseWindow(s string) (Window, error) {
+	bounds := strings.Split(strings.TrimSpace(s), "-")
+	if len(bounds) != 2 { //nolint:gomnd
+		return Window{}, errors.Errorf("invalid window %q: expected HH:MM-HH:MM format", s)
+	}
+
+	var w Window
+	for i, b := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(b))
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+		}
+		d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			w.Start = d
+		} else {
+			w.End = d
+		}
+	}
+	return w, nil
+}
+
+func (w Window) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+func (w Window) startsOn(d time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range w.Weekdays {
+		if wd == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Close returns the end of the window containing t and whether t is in the window.
+func (w Window) Close(t time.Time) (time.Time, bool) {
+	t = t.In(w.location())
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	sinceMidnight := t.Sub(midnight)
+
+	if w.Start < w.End {
+		if w.startsOn(t.Weekday()) && sinceMidnight >= w.Start && sinceMidnight < w.End {
+			return midnight.Add(w.End), true
+		}
+		return time.Time{}, false
+	}
+
+	// The window spans midnight.
+	if w.startsOn(t.Weekday()) && sinceMidnight >= w.Start {
+		return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(w.End), true
+	}
+	if w.startsOn(t.AddDate(0, 0, -1).Weekday()) && sinceMidnight < w.End {
+		return midnight.Add(w.End), true
+	}
+	return time.Time{}, false
+}
+
+// Contains returns true if t is in the window.
+func (w Window) Contains(t time.Time) bool {
+	_, ok := w.Close(t)
+	return ok
+}
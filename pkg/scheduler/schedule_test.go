@@ -0,0 +1,104 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("Invalid expressions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *"} {
+			_, err := ParseSchedule(expr)
+			g.Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		// 2024-01-03 is a Wednesday.
+		from := time.Date(2024, 1, 3, 10, 17, 30, 0, time.UTC)
+		tests := map[string]time.Time{
+			"*/15 * * * *":  time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC),
+			"0 2 * * *":     time.Date(2024, 1, 4, 2, 0, 0, 0, time.UTC),
+			"30 1 * * 6,7":  time.Date(2024, 1, 6, 1, 30, 0, 0, time.UTC),
+			"0 0 1 */2 *":   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			"0 12 15 * 1":   time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC),
+			"@hourly":       time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC),
+			"17 10 3 1 *":   time.Date(2025, 1, 3, 10, 17, 0, 0, time.UTC),
+			"0 22-23 * * *": time.Date(2024, 1, 3, 22, 0, 0, 0, time.UTC),
+		}
+		for expr, expected := range tests {
+			s, err := ParseSchedule(expr)
+			g.Expect(err).NotTo(HaveOccurred(), expr)
+			s.Location = time.UTC
+			g.Expect(s.Next(from)).To(Equal(expected), expr)
+		}
+	})
+}
+
+func TestWindow(t *testing.T) {
+	t.Run("Invalid windows", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		for _, s := range []string{"", "22:00", "22:00-25:00", "a-b"} {
+			_, err := ParseWindow(s)
+			g.Expect(err).To(HaveOccurred(), s)
+		}
+	})
+
+	t.Run("Window within a day", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		w, err := ParseWindow("01:00-05:30")
+		g.Expect(err).NotTo(HaveOccurred())
+		w.Location = time.UTC
+
+		closeAt, ok := w.Close(time.Date(2024, 1, 3, 3, 0, 0, 0, time.UTC))
+		g.Expect(ok).To(BeTrue())
+		g.Expect(closeAt).To(Equal(time.Date(2024, 1, 3, 5, 30, 0, 0, time.UTC)))
+		g.Expect(w.Contains(time.Date(2024, 1, 3, 5, 30, 0, 0, time.UTC))).To(BeFalse())
+		g.Expect(w.Contains(time.Date(2024, 1, 3, 0, 59, 0, 0, time.UTC))).To(BeFalse())
+	})
+
+	t.Run("Window spanning midnight", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		w, err := ParseWindow("22:00-04:00")
+		g.Expect(err).NotTo(HaveOccurred())
+		w.Location = time.UTC
+		w.Weekdays = []time.Weekday{time.Friday}
+
+		// 2024-01-05 is a Friday.
+		closeAt, ok := w.Close(time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC))
+		g.Expect(ok).To(BeTrue())
+		g.Expect(closeAt).To(Equal(time.Date(2024, 1, 6, 4, 0, 0, 0, time.UTC)))
+
+		closeAt, ok = w.Close(time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC))
+		g.Expect(ok).To(BeTrue())
+		g.Expect(closeAt).To(Equal(time.Date(2024, 1, 6, 4, 0, 0, 0, time.UTC)))
+
+		g.Expect(w.Contains(time.Date(2024, 1, 5, 2, 0, 0, 0, time.UTC))).To(BeFalse())
+		g.Expect(w.Contains(time.Date(2024, 1, 6, 23, 0, 0, 0, time.UTC))).To(BeFalse())
+	})
+}
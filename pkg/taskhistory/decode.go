@@ -0,0 +1,134 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskhistory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+// resultTypes holds the constructors of the result types of the endpoints.
+var resultTypes = map[types.APIEndpoint]func() interface{}{
+	api.EndpointAddBroker:             func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointDemoteBroker:          func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointFixOfflineReplicas:    func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointProposals:             func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointRebalance:             func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointRemoveBroker:          func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointTopicConfiguration:    func() interface{} { return &types.OptimizationResult{} },
+	api.EndpointAdmin:                 func() interface{} { return &types.AdminResult{} },
+	api.EndpointBootstrap:             func() interface{} { return &types.BootstrapResult{} },
+	api.EndpointKafkaClusterLoad:      func() interface{} { return &types.BrokerStats{} },
+	api.EndpointKafkaClusterState:     func() interface{} { return &types.KafkaClusterState{} },
+	api.EndpointKafkaPartitionLoad:    func() interface{} { return &types.PartitionLoadState{} },
+	api.EndpointPauseSampling:         func() interface{} { return &types.SamplingResult{} },
+	api.EndpointResumeSampling:        func() interface{} { return &types.SamplingResult{} },
+	api.EndpointReview:                func() interface{} { return &types.ReviewResult{} },
+	api.EndpointReviewBoard:           func() interface{} { return &types.ReviewResult{} },
+	api.EndpointRightsize:             func() interface{} { return &types.RightsizeResult{} },
+	api.EndpointState:                 func() interface{} { return &types.StateResult{} },
+	api.EndpointStopProposalExecution: func() interface{} { return &types.StopProposalResult{} },
+	api.EndpointTrain:                 func() interface{} { return &types.TrainResult{} },
+	api.EndpointUserTasks:             func() interface{} { return &types.UserTaskState{} },
+}
+
+// dryRunEndpoints are the endpoints which run in dry-run mode unless the dryrun parameter is set to false.
+var dryRunEndpoints = map[types.APIEndpoint]bool{
+	api.EndpointAddBroker:          true,
+	api.EndpointDemoteBroker:       true,
+	api.EndpointFixOfflineReplicas: true,
+	api.EndpointRebalance:          true,
+	api.EndpointRemoveBroker:       true,
+	api.EndpointTopicConfiguration: true,
+}
+
+// EndpointFromURL returns the endpoint of the request URL of a user task.
+func EndpointFromURL(requestURL string) (types.APIEndpoint, error) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse request URL %q: %w", requestURL, err)
+	}
+	e := path.Base(strings.TrimSuffix(u.Path, "/"))
+	if e == "." || e == "/" {
+		return "", fmt.Errorf("request URL %q does not contain an endpoint", requestURL)
+	}
+	return types.APIEndpoint(strings.ToUpper(e)), nil
+}
+
+// isDryRun returns true if the request URL of a user task belongs to an endpoint which did not execute proposals
+// because it was run in dry-run mode.
+func isDryRun(e types.APIEndpoint, requestURL string) bool {
+	if e == api.EndpointProposals {
+		return true
+	}
+	if !dryRunEndpoints[e] {
+		return false
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return true
+	}
+	return !strings.EqualFold(u.Query().Get("dryrun"), "false")
+}
+
+// DecodeResult decodes the original response of the user task into the result type of its endpoint, e.g.
+// *types.OptimizationResult for REBALANCE tasks. It returns nil if the original response is not available.
+// Failed tasks are reported as *types.APIError and responses of unknown endpoints as json.RawMessage.
+func DecodeResult(info types.UserTaskInfo) (interface{}, error) {
+	e, err := EndpointFromURL(info.RequestURL)
+	if err != nil {
+		return nil, err
+	}
+	result, apiErr, err := decodeResult(e, info.OriginalResponse)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return result, err
+}
+
+func decodeResult(e types.APIEndpoint, original string) (interface{}, *types.APIError, error) {
+	original = strings.TrimSpace(original)
+	if original == "" {
+		return nil, nil, nil
+	}
+	data := []byte(original)
+	if !json.Valid(data) {
+		return nil, nil, errors.New("original response is not valid JSON")
+	}
+
+	apiErr := &types.APIError{}
+	if err := json.Unmarshal(data, apiErr); err == nil && (apiErr.ErrorMessage != "" || apiErr.Message != "") {
+		return nil, apiErr, nil
+	}
+
+	newResult, ok := resultTypes[e]
+	if !ok {
+		return json.RawMessage(data), nil, nil
+	}
+	result := newResult()
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode original response of %s request: %w", e, err)
+	}
+	return result, nil, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskhistory
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV        Format = "csv"
+	FormatJSONLines  Format = "jsonl"
+	exportTimeLayout        = time.RFC3339Nano
+)
+
+// Format of the exported history.
+type Format string
+
+var csvHeader = []string{
+	"user_task_id", "endpoint", "client", "status", "start", "end", "duration_ms", "dry_run",
+	"data_to_move_mb", "intra_broker_data_to_move_mb", "replica_movements", "intra_broker_replica_movements",
+	"leader_movements", "error", "request_url",
+}
+
+// Writer writes history entries in an export format.
+type Writer interface {
+	Write(e Entry) error
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// NewWriter returns a Writer exporting entries to w in the provided format.
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// Export writes the history of the user tasks selected by the options to w in the provided format.
+func Export(ctx context.Context, client Client, opts Options, w io.Writer, format Format) error {
+	writer, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err = Each(ctx, client, opts, writer.Write); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWriter) Write(e Entry) error {
+	if !c.headerWritten {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	var end, duration string
+	if !e.End.IsZero() {
+		end = e.End.UTC().Format(exportTimeLayout)
+		duration = strconv.FormatInt(e.Duration().Milliseconds(), 10)
+	}
+	return c.w.Write([]string{
+		e.UserTaskID,
+		e.Endpoint.String(),
+		e.Client,
+		e.Status.String(),
+		e.Start.UTC().Format(exportTimeLayout),
+		end,
+		duration,
+		strconv.FormatBool(e.DryRun),
+		strconv.FormatInt(e.DataToMoveMB, 10),
+		strconv.FormatInt(e.IntraBrokerDataToMoveMB, 10),
+		strconv.FormatInt(int64(e.NumReplicaMovements), 10),
+		strconv.FormatInt(int64(e.NumIntraBrokerReplicaMovements), 10),
+		strconv.FormatInt(int64(e.NumLeaderMovements), 10),
+		e.Error,
+		e.RequestURL,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.headerWritten {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonLinesEntry is the JSON representation of an Entry.
+type jsonLinesEntry struct {
+	UserTaskID                     string      `json:"userTaskID"`
+	Endpoint                       string      `json:"endpoint"`
+	Client                         string      `json:"client,omitempty"`
+	Status                         string      `json:"status"`
+	Start                          time.Time   `json:"start"`
+	End                            *time.Time  `json:"end,omitempty"`
+	DurationMs                     *int64      `json:"durationMs,omitempty"`
+	DryRun                         bool        `json:"dryRun"`
+	DataToMoveMB                   int64       `json:"dataToMoveMB"`
+	IntraBrokerDataToMoveMB        int64       `json:"intraBrokerDataToMoveMB"`
+	NumReplicaMovements            int32       `json:"numReplicaMovements"`
+	NumIntraBrokerReplicaMovements int32       `json:"numIntraBrokerReplicaMovements"`
+	NumLeaderMovements             int32       `json:"numLeaderMovements"`
+	Error                          string      `json:"error,omitempty"`
+	RequestURL                     string      `json:"requestURL"`
+	Result                         interface{} `json:"result,omitempty"`
+}
+
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonLinesWriter) Write(e Entry) error {
+	entry := jsonLinesEntry{
+		UserTaskID:                     e.UserTaskID,
+		Endpoint:                       e.Endpoint.String(),
+		Client:                         e.Client,
+		Status:                         e.Status.String(),
+		Start:                          e.Start.UTC(),
+		DryRun:                         e.DryRun,
+		DataToMoveMB:                   e.DataToMoveMB,
+		IntraBrokerDataToMoveMB:        e.IntraBrokerDataToMoveMB,
+		NumReplicaMovements:            e.NumReplicaMovements,
+		NumIntraBrokerReplicaMovements: e.NumIntraBrokerReplicaMovements,
+		NumLeaderMovements:             e.NumLeaderMovements,
+		Error:                          e.Error,
+		RequestURL:                     e.RequestURL,
+		Result:                         e.Result,
+	}
+	if !e.End.IsZero() {
+		end := e.End.UTC()
+		duration := e.Duration().Milliseconds()
+		entry.End = &end
+		entry.DurationMs = &duration
+	}
+	return j.enc.Encode(entry)
+}
+
+func (j *jsonLinesWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskhistory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+const (
+	DefaultPageSize = 20
+
+	auditClockSkew = time.Minute
+)
+
+// Client is implemented by clients which are able to retrieve user tasks from Cruise Control.
+type Client interface {
+	UserTasks(ctx context.Context, r *api.UserTasksRequest) (*api.UserTasksResponse, error)
+}
+
+// Options select the user tasks of the history.
+type Options struct {
+	// Filters passed to the UserTasks request. Empty filters match every task.
+	ClientIDs   []string
+	Endpoints   []types.APIEndpoint
+	Types       []types.UserTaskStatus
+	UserTaskIDs []string
+	// PageSize is the number of user tasks whose original response is requested at once.
+	// DefaultPageSize is used if not set.
+	PageSize int
+	// Audit is used for determining the end time of the tasks as Cruise Control only reports their start time.
+	// The end of the last audited request of a task is used as its end time, which is the time the proposals were
+	// returned for tasks executing them. End times are unknown if not set.
+	Audit audit.Store
+}
+
+func (o Options) withDefaults() Options {
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultPageSize
+	}
+	return o
+}
+
+// Entry describes a user task of the history.
+type Entry struct {
+	UserTaskID string
+	Endpoint   types.APIEndpoint
+	RequestURL string
+	Client     string
+	Status     types.UserTaskStatus
+	Start      time.Time
+	// End of the task. It is zero if unknown.
+	End time.Time
+	// DryRun is true if the task did not execute any proposals.
+	DryRun bool
+	// Result is the original response of the task decoded into the result type of its endpoint.
+	// See DecodeResult.
+	Result interface{}
+	// Error holds the error the task failed with or the error decoding its original response.
+	Error string
+	// Data to move and the number of movements of tasks returning optimization results.
+	DataToMoveMB                   int64
+	IntraBrokerDataToMoveMB        int64
+	NumReplicaMovements            int32
+	NumIntraBrokerReplicaMovements int32
+	NumLeaderMovements             int32
+}
+
+// Duration returns the time the task took. It is zero if the end of the task is unknown.
+func (e Entry) Duration() time.Duration {
+	if e.End.IsZero() {
+		return 0
+	}
+	return e.End.Sub(e.Start)
+}
+
+// NewEntry returns the history entry of the user task.
+func NewEntry(info types.UserTaskInfo) Entry {
+	entry := Entry{
+		UserTaskID: info.UserTaskID,
+		RequestURL: info.RequestURL,
+		Client:     info.Client,
+		Status:     info.Status,
+		Start:      info.StartMs.Time,
+	}
+
+	e, err := EndpointFromURL(info.RequestURL)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Endpoint = e
+	entry.DryRun = isDryRun(e, info.RequestURL)
+
+	result, apiErr, err := decodeResult(e, info.OriginalResponse)
+	switch {
+	case apiErr != nil:
+		entry.Error = apiErr.Error()
+	case err != nil:
+		entry.Error = err.Error()
+	}
+	entry.Result = result
+
+	if o, ok := result.(*types.OptimizationResult); ok {
+		entry.DataToMoveMB = o.Summary.DataToMoveMB
+		entry.IntraBrokerDataToMoveMB = o.Summary.IntraBrokerDataToMoveMB
+		entry.NumReplicaMovements = o.Summary.NumReplicaMovements
+		entry.NumIntraBrokerReplicaMovements = o.Summary.NumIntraBrokerReplicaMovements
+		entry.NumLeaderMovements = o.Summary.NumLeaderMovements
+	}
+	return entry
+}
+
+// Each pages through the user tasks selected by the options in the order they were started and calls f with
+// the history entry of each of them. Iteration stops at the first error returned by f.
+func Each(ctx context.Context, client Client, opts Options, f func(Entry) error) error {
+	opts = opts.withDefaults()
+
+	// The user tasks are listed without their original responses first, which are then requested page by page
+	// as they can be large.
+	req := api.UserTasksRequestWithDefaults()
+	req.ClientIDs = opts.ClientIDs
+	req.Endpoints = opts.Endpoints
+	req.Types = opts.Types
+	req.UserTaskIDs = opts.UserTaskIDs
+
+	resp, err := client.UserTasks(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to list user tasks: %w", err)
+	}
+	if resp.Result == nil {
+		return nil
+	}
+	tasks := resp.Result.UserTasks
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].StartMs.Before(tasks[j].StartMs.Time) })
+
+	for start := 0; start < len(tasks); start += opts.PageSize {
+		page := tasks[start:min(start+opts.PageSize, len(tasks))]
+		entries, err := fetchPage(ctx, client, page, opts.Audit)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err = f(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Fetch returns the history entries of the user tasks selected by the options in the order they were started.
+func Fetch(ctx context.Context, client Client, opts Options) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	err := Each(ctx, client, opts, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+func fetchPage(ctx context.Context, client Client, page []types.UserTaskInfo, store audit.Store) ([]Entry, error) {
+	req := api.UserTasksRequestWithDefaults()
+	req.FetchCompletedTasks = true
+	for _, t := range page {
+		req.UserTaskIDs = append(req.UserTaskIDs, t.UserTaskID)
+	}
+
+	resp, err := client.UserTasks(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get original responses of user tasks: %w", err)
+	}
+	fetched := make(map[string]types.UserTaskInfo)
+	if resp.Result != nil {
+		for _, t := range resp.Result.UserTasks {
+			fetched[t.UserTaskID] = t
+		}
+	}
+
+	ends, err := endTimes(ctx, store, page)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(page))
+	for _, t := range page {
+		// Tasks might have expired since they were listed, in which case what is already known is used.
+		if f, ok := fetched[t.UserTaskID]; ok {
+			t = f
+		}
+		e := NewEntry(t)
+		if end, ok := ends[t.UserTaskID]; ok && t.Status != types.UserTaskStatusActive &&
+			t.Status != types.UserTaskStatusInExecution {
+			e.End = end
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// endTimes returns the end of the last audited request of each task.
+func endTimes(ctx context.Context, store audit.Store, page []types.UserTaskInfo) (map[string]time.Time, error) {
+	ends := make(map[string]time.Time)
+	if store == nil || len(page) == 0 {
+		return ends, nil
+	}
+
+	since := page[0].StartMs.Time
+	ids := make(map[string]bool, len(page))
+	for _, t := range page {
+		ids[t.UserTaskID] = true
+		if t.StartMs.Before(since) {
+			since = t.StartMs.Time
+		}
+	}
+
+	// Requests are started slightly before Cruise Control creates their user task.
+	records, err := store.Query(ctx, audit.Query{Since: since.Add(-auditClockSkew)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	for _, r := range records {
+		if !ids[r.UserTaskID] {
+			continue
+		}
+		if end := r.Time.Add(r.Duration); end.After(ends[r.UserTaskID]) {
+			ends[r.UserTaskID] = end
+		}
+	}
+	return ends, nil
+}
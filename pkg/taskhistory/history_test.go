@@ -0,0 +1,159 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskhistory
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/banzaicloud/go-cruise-control/pkg/api"
+	"github.com/banzaicloud/go-cruise-control/pkg/audit"
+	"github.com/banzaicloud/go-cruise-control/pkg/types"
+)
+
+type fakeClient struct {
+	tasks    []types.UserTaskInfo
+	requests []*api.UserTasksRequest
+}
+
+func (f *fakeClient) UserTasks(_ context.Context, r *api.UserTasksRequest) (*api.UserTasksResponse, error) {
+	f.requests = append(f.requests, r)
+
+	ids := make(map[string]bool)
+	for _, id := range r.UserTaskIDs {
+		ids[id] = true
+	}
+	tasks := make([]types.UserTaskInfo, 0)
+	for _, t := range f.tasks {
+		if len(ids) > 0 && !ids[t.UserTaskID] {
+			continue
+		}
+		if !r.FetchCompletedTasks {
+			t.OriginalResponse = ""
+		}
+		tasks = append(tasks, t)
+	}
+	return &api.UserTasksResponse{Result: &types.UserTaskState{UserTasks: tasks}}, nil
+}
+
+func newTestClient(start time.Time) *fakeClient {
+	return &fakeClient{tasks: []types.UserTaskInfo{
+		{
+			UserTaskID:       "rebalance",
+			RequestURL:       "http://localhost:8090/kafkacruisecontrol/rebalance?dryrun=false&json=true",
+			StartMs:          types.DateTime{Time: start.Add(time.Minute)},
+			Status:           types.UserTaskStatusCompleted,
+			OriginalResponse: `{"summary":{"dataToMoveMB":1024,"numReplicaMovements":12,"numLeaderMovements":3},"version":1}`,
+		},
+		{
+			UserTaskID:       "remove",
+			RequestURL:       "http://localhost:8090/kafkacruisecontrol/remove_broker?brokerid=1",
+			StartMs:          types.DateTime{Time: start.Add(2 * time.Minute)},
+			Status:           types.UserTaskStatusCompletedWithError,
+			OriginalResponse: `{"errorMessage":"broker 1 does not exist","version":1}`,
+		},
+		{
+			UserTaskID: "state",
+			RequestURL: "http://localhost:8090/kafkacruisecontrol/state",
+			StartMs:    types.DateTime{Time: start},
+			Status:     types.UserTaskStatusActive,
+		},
+	}}
+}
+
+func TestFetch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newTestClient(start)
+	store := &audit.MemoryStore{}
+	g.Expect(store.Append(context.Background(), audit.Record{
+		Time:       start.Add(time.Minute),
+		Duration:   30 * time.Second,
+		UserTaskID: "rebalance",
+	})).To(Succeed())
+
+	entries, err := Fetch(context.Background(), client, Options{PageSize: 2, Audit: store})
+	g.Expect(err).NotTo(HaveOccurred())
+	// One request for listing the tasks and one for each page.
+	g.Expect(client.requests).To(HaveLen(3))
+
+	g.Expect(entries).To(HaveLen(3))
+	g.Expect(entries[0].UserTaskID).To(Equal("state"))
+	g.Expect(entries[0].Endpoint).To(Equal(api.EndpointState))
+	g.Expect(entries[0].Result).To(BeNil())
+
+	g.Expect(entries[1].Endpoint).To(Equal(api.EndpointRebalance))
+	g.Expect(entries[1].DryRun).To(BeFalse())
+	g.Expect(entries[1].Result).To(BeAssignableToTypeOf(&types.OptimizationResult{}))
+	g.Expect(entries[1].DataToMoveMB).To(Equal(int64(1024)))
+	g.Expect(entries[1].NumReplicaMovements).To(Equal(int32(12)))
+	g.Expect(entries[1].Duration()).To(Equal(30 * time.Second))
+
+	g.Expect(entries[2].Endpoint).To(Equal(api.EndpointRemoveBroker))
+	g.Expect(entries[2].DryRun).To(BeTrue())
+	g.Expect(entries[2].Error).To(Equal("broker 1 does not exist"))
+	g.Expect(entries[2].Duration()).To(BeZero())
+}
+
+func TestExport(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("CSV", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var buf bytes.Buffer
+		g.Expect(Export(context.Background(), newTestClient(start), Options{}, &buf, FormatCSV)).To(Succeed())
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(rows).To(HaveLen(4))
+		g.Expect(rows[0]).To(Equal(csvHeader))
+		g.Expect(rows[2][:9]).To(Equal([]string{
+			"rebalance", "REBALANCE", "", "Completed", "2024-01-01T00:01:00Z", "", "", "false", "1024",
+		}))
+	})
+
+	t.Run("JSON lines", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		var buf bytes.Buffer
+		g.Expect(Export(context.Background(), newTestClient(start), Options{}, &buf, FormatJSONLines)).To(Succeed())
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		g.Expect(lines).To(HaveLen(3))
+
+		var entry map[string]interface{}
+		g.Expect(json.Unmarshal(lines[1], &entry)).To(Succeed())
+		g.Expect(entry["endpoint"]).To(Equal("REBALANCE"))
+		g.Expect(entry["dataToMoveMB"]).To(BeEquivalentTo(1024))
+		g.Expect(entry["result"]).NotTo(BeNil())
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		_, err := NewWriter(&bytes.Buffer{}, "xml")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
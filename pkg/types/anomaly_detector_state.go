@@ -16,6 +16,14 @@ limitations under the License.
 
 package types
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
 type AnomalyDetectorState struct {
 	SelfHealingEnabled  []AnomalyType `json:"selfHealingEnabled"`
 	SelfHealingDisabled []AnomalyType `json:"selfHealingDisabled"`
@@ -112,16 +120,129 @@ func (g *AnomalyStatus) UnmarshalText(data []byte) error {
 }
 
 type AnomalyDetails struct {
-	StatusUpdateMs         int64            `json:"statusUpdateMs"`
-	DetectionMs            int64            `json:"detectionMs"`
-	Status                 AnomalyStatus    `json:"status"`
-	AnomalyID              string           `json:"anomalyId"` // It might be a separate type
-	FixableViolatedGoals   []Goal           `json:"fixableViolatedGoals"`
-	UnfixableViolatedGoals []Goal           `json:"unfixableViolatedGoals"`
-	OptimizationResult     string           `json:"optimizationResult"` // It might be a separate type
-	FailedBrokersByTimeMs  map[string]int64 `json:"failedBrokersByTimeMs"`
-	FailedDisksByTimeMs    map[string]int64 `json:"failedDisksByTimeMs"`
-	Description            string           `json:"description"`
+	StatusUpdateMs         int64                         `json:"statusUpdateMs"`
+	DetectionMs            int64                         `json:"detectionMs"`
+	Status                 AnomalyStatus                 `json:"status"`
+	AnomalyID              AnomalyID                     `json:"anomalyId"`
+	FixableViolatedGoals   []Goal                        `json:"fixableViolatedGoals"`
+	UnfixableViolatedGoals []Goal                        `json:"unfixableViolatedGoals"`
+	OptimizationResult     AnomalyOptimizationResult     `json:"optimizationResult"`
+	FailedBrokersByTimeMs  map[int32]DateTime            `json:"failedBrokersByTimeMs"`
+	FailedDisksByTimeMs    map[int32]map[string]DateTime `json:"failedDisksByTimeMs"`
+	Description            string                        `json:"description"`
+}
+
+// DetectedAt returns the time when the anomaly was detected.
+func (d AnomalyDetails) DetectedAt() time.Time {
+	return time.UnixMilli(d.DetectionMs)
+}
+
+// StatusUpdatedAt returns the time of the latest status change of the anomaly.
+func (d AnomalyDetails) StatusUpdatedAt() time.Time {
+	return time.UnixMilli(d.StatusUpdateMs)
+}
+
+// AnomalyID is the unique identifier Cruise Control assigns to a detected anomaly.
+type AnomalyID string
+
+func (id AnomalyID) String() string {
+	return string(id)
+}
+
+// AnomalyOptimizationResult holds the optimization result Cruise Control attaches to anomalies which
+// it tried to fix using self-healing. Cruise Control embeds the result as a string which contains
+// either a JSON document or plain text, therefore the original value is always kept in Raw while
+// Result is only populated if the embedded value could be decoded.
+type AnomalyOptimizationResult struct {
+	Raw    string
+	Result *OptimizationResult
+}
+
+func (r AnomalyOptimizationResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Raw)
+}
+
+func (r *AnomalyOptimizationResult) UnmarshalJSON(data []byte) error {
+	r.Raw = ""
+	r.Result = nil
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")):
+		return nil
+	case trimmed[0] == '{':
+		r.Raw = string(trimmed)
+	default:
+		if err := json.Unmarshal(trimmed, &r.Raw); err != nil {
+			return fmt.Errorf("failed to parse anomaly optimization result: %w", err)
+		}
+	}
+
+	embedded := strings.TrimSpace(r.Raw)
+	if !strings.HasPrefix(embedded, "{") {
+		return nil
+	}
+
+	result := &OptimizationResult{}
+	if err := json.Unmarshal([]byte(embedded), result); err != nil {
+		// The embedded result is informational, failing to decode it must not make the whole state unusable.
+		return nil //nolint:nilerr
+	}
+	r.Result = result
+	return nil
+}
+
+// Decoded returns true if the embedded optimization result was successfully decoded.
+func (r AnomalyOptimizationResult) Decoded() bool {
+	return r.Result != nil
+}
+
+// Anomaly is an AnomalyDetails annotated with the type of the anomaly.
+type Anomaly struct {
+	AnomalyDetails
+
+	Type AnomalyType
+}
+
+// Anomalies returns all the recent anomalies reported by the anomaly detector with their type.
+func (s AnomalyDetectorState) Anomalies() []Anomaly {
+	recent := []struct {
+		t AnomalyType
+		d []AnomalyDetails
+	}{
+		{AnomalyTypeGoalViolation, s.RecentGoalViolations},
+		{AnomalyTypeBrokerFailure, s.RecentBrokerFailures},
+		{AnomalyTypeMetricAnomaly, s.RecentMetricAnomalies},
+		{AnomalyTypeDiskFailure, s.RecentDiskFailures},
+		{AnomalyTypeTopicAnomaly, s.RecentTopicAnomalies},
+		{AnomalyTypeMaintenanceEvent, s.RecentMaintenanceEvents},
+	}
+
+	anomalies := make([]Anomaly, 0)
+	for _, r := range recent {
+		for _, d := range r.d {
+			anomalies = append(anomalies, Anomaly{AnomalyDetails: d, Type: r.t})
+		}
+	}
+	return anomalies
+}
+
+// AnomaliesByType returns the recent anomalies grouped by their type.
+func (s AnomalyDetectorState) AnomaliesByType() map[AnomalyType][]Anomaly {
+	grouped := make(map[AnomalyType][]Anomaly)
+	for _, a := range s.Anomalies() {
+		grouped[a.Type] = append(grouped[a.Type], a)
+	}
+	return grouped
+}
+
+// AnomaliesByStatus returns the recent anomalies grouped by their status.
+func (s AnomalyDetectorState) AnomaliesByStatus() map[AnomalyStatus][]Anomaly {
+	grouped := make(map[AnomalyStatus][]Anomaly)
+	for _, a := range s.Anomalies() {
+		grouped[a.Status] = append(grouped[a.Status], a)
+	}
+	return grouped
 }
 
 type AnomalyMetrics struct {
@@ -0,0 +1,99 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+const anomalyDetectorStateJSON = `{
+  "recentGoalViolations": [
+    {
+      "anomalyId": "8a9c2a3e-5f4c-4e6b-9c0d-1f2e3d4c5b6a",
+      "status": "FIX_STARTED",
+      "detectionMs": 1700000000000,
+      "statusUpdateMs": 1700000005000,
+      "fixableViolatedGoals": ["RackAwareGoal"],
+      "optimizationResult": "{\"proposals\":[{\"topicPartition\":{\"topic\":\"test\",\"partition\":1},\"oldLeader\":0,\"oldReplicas\":[0,1],\"newReplicas\":[2,1]}],\"summary\":{\"numReplicaMovements\":1},\"version\":1}"
+    }
+  ],
+  "recentBrokerFailures": [
+    {
+      "anomalyId": "1b2c3d4e-0000-4e6b-9c0d-1f2e3d4c5b6a",
+      "status": "FIX_FAILED_TO_START",
+      "failedBrokersByTimeMs": {"2": 1700000000000}
+    }
+  ],
+  "recentDiskFailures": [
+    {
+      "anomalyId": "2c3d4e5f-0000-4e6b-9c0d-1f2e3d4c5b6a",
+      "status": "FIX_STARTED",
+      "failedDisksByTimeMs": {"1": {"/var/lib/kafka/data-1": 1700000000000}},
+      "optimizationResult": "Cluster load after self-healing:"
+    }
+  ]
+}`
+
+func TestAnomalyDetectorState(t *testing.T) {
+	t.Run("Unmarshal typed anomaly details", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := AnomalyDetectorState{}
+		g.Expect(json.Unmarshal([]byte(anomalyDetectorStateJSON), &state)).To(Succeed())
+
+		g.Expect(state.RecentGoalViolations).To(HaveLen(1))
+		violation := state.RecentGoalViolations[0]
+		g.Expect(violation.AnomalyID).To(Equal(AnomalyID("8a9c2a3e-5f4c-4e6b-9c0d-1f2e3d4c5b6a")))
+		g.Expect(violation.DetectedAt()).To(Equal(time.UnixMilli(1700000000000)))
+		g.Expect(violation.OptimizationResult.Decoded()).To(BeTrue())
+		g.Expect(violation.OptimizationResult.Result.Proposals).To(HaveLen(1))
+		g.Expect(violation.OptimizationResult.Result.Proposals[0].NewReplicas).To(Equal([]int32{2, 1}))
+
+		g.Expect(state.RecentBrokerFailures[0].FailedBrokersByTimeMs).To(HaveKey(int32(2)))
+		g.Expect(state.RecentBrokerFailures[0].FailedBrokersByTimeMs[2].Time).To(Equal(time.UnixMilli(1700000000000)))
+
+		disks := state.RecentDiskFailures[0].FailedDisksByTimeMs
+		g.Expect(disks).To(HaveKey(int32(1)))
+		g.Expect(disks[1]).To(HaveKey("/var/lib/kafka/data-1"))
+
+		plain := state.RecentDiskFailures[0].OptimizationResult
+		g.Expect(plain.Decoded()).To(BeFalse())
+		g.Expect(plain.Raw).To(Equal("Cluster load after self-healing:"))
+	})
+
+	t.Run("Group anomalies", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		state := AnomalyDetectorState{}
+		g.Expect(json.Unmarshal([]byte(anomalyDetectorStateJSON), &state)).To(Succeed())
+
+		g.Expect(state.Anomalies()).To(HaveLen(3))
+
+		byType := state.AnomaliesByType()
+		g.Expect(byType[AnomalyTypeGoalViolation]).To(HaveLen(1))
+		g.Expect(byType[AnomalyTypeBrokerFailure]).To(HaveLen(1))
+		g.Expect(byType[AnomalyTypeDiskFailure]).To(HaveLen(1))
+
+		byStatus := state.AnomaliesByStatus()
+		g.Expect(byStatus[AnomalyStatusFixStarted]).To(HaveLen(2))
+		g.Expect(byStatus[AnomalyStatusFixFailedToStart]).To(HaveLen(1))
+	})
+}
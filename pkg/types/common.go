@@ -26,6 +26,8 @@ const (
 	UserTaskIDHTTPHeader           = "User-Task-ID"
 	CruiseControlVersionHTTPHeader = "Cruise-Control-Version"
 	DateHTTPHeader                 = "Date"
+	ContentTypeHTTPHeader          = "Content-Type"
+	JSONSchemaHTTPHeader           = "x-json-schema"
 
 	Undefined = "UNDEFINED"
 )
@@ -75,6 +77,11 @@ type GenericResponse struct {
 	RequestURL           string
 	Progress             *ProgressResult
 	Error                *APIError
+	// ResponseSchema holds the JSON schema of the response if it was requested.
+	ResponseSchema string
+	// Diagnostics holds the differences between the response and the Go type it was decoded into
+	// if strict decoding or response schema checking is enabled for the client.
+	Diagnostics *ResponseDiagnostics
 }
 
 func (r *GenericResponse) UnmarshalResponse(resp *http.Response) error {
@@ -83,9 +90,41 @@ func (r *GenericResponse) UnmarshalResponse(resp *http.Response) error {
 	r.Date = resp.Header.Get(DateHTTPHeader)
 	r.StatusCode = resp.StatusCode
 	r.RequestURL = resp.Request.URL.String()
+	r.ResponseSchema = resp.Header.Get(JSONSchemaHTTPHeader)
 	return nil
 }
 
+// AddDiagnostics merges the provided diagnostics into the diagnostics of the response.
+func (r *GenericResponse) AddDiagnostics(d *ResponseDiagnostics) {
+	if d.Empty() {
+		return
+	}
+	if r.Diagnostics == nil {
+		r.Diagnostics = &ResponseDiagnostics{}
+	}
+	r.Diagnostics.UnknownFields = appendUnique(r.Diagnostics.UnknownFields, d.UnknownFields...)
+	r.Diagnostics.MissingFields = appendUnique(r.Diagnostics.MissingFields, d.MissingFields...)
+}
+
+func appendUnique(s []string, v ...string) []string {
+	seen := make(map[string]bool, len(s))
+	for _, i := range s {
+		seen[i] = true
+	}
+	for _, i := range v {
+		if !seen[i] {
+			seen[i] = true
+			s = append(s, i)
+		}
+	}
+	return s
+}
+
+// UserTaskID returns the ID of the Cruise Control user task which the response belongs to.
+func (r *GenericResponse) UserTaskID() string {
+	return r.TaskID
+}
+
 func (r *GenericResponse) InProgress() bool {
 	return r.Progress != nil
 }
@@ -94,8 +133,13 @@ func (r *GenericResponse) Failed() bool {
 	return r.Error != nil
 }
 
+// Err returns the typed error for the failed request or nil if the request did not fail.
+// See ClassifyError for the possible error types.
 func (r *GenericResponse) Err() error {
-	return r.Error
+	if r.Error == nil {
+		return nil
+	}
+	return ClassifyError(r.StatusCode, r.RequestURL, r.TaskID, r.Error)
 }
 
 type APIError struct {
@@ -138,6 +182,8 @@ func (e APIEndpoint) Path() string {
 	return strings.ToLower(e.String())
 }
 
+// MarshalJSON encodes the endpoint as a JSON string with its name in upper case, so records holding endpoints
+// can be encoded and decoded again.
 func (e APIEndpoint) MarshalJSON() ([]byte, error) {
-	return []byte(strings.ToUpper(e.String())), nil
+	return []byte(addQuotes(strings.ToUpper(e.String()))), nil
 }
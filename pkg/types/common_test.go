@@ -0,0 +1,40 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIEndpointJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type record struct {
+		Endpoint APIEndpoint `json:"endpoint"`
+	}
+
+	data, err := json.Marshal(record{Endpoint: "rebalance"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(data)).To(Equal(`{"endpoint":"REBALANCE"}`))
+
+	var decoded record
+	g.Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+	g.Expect(decoded.Endpoint).To(Equal(APIEndpoint("REBALANCE")))
+}
@@ -0,0 +1,317 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	notReadyPatterns = []string{
+		"notenoughvalidwindowsexception",
+		"not enough valid windows",
+		"insufficient number of valid windows",
+		"no valid partition window",
+		"no window available",
+		"bootstrapping",
+		"load monitor is not ready",
+		"loadmonitor is not ready",
+	}
+	ongoingExecutionPatterns = []string{
+		"ongoingexecutionexception",
+		"ongoing execution",
+		"execution in progress",
+		"is already in progress",
+	}
+	permissionDeniedPatterns = []string{
+		"permission denied",
+		"not authorized",
+		"unauthorized",
+		"forbidden",
+		"accessdeniedexception",
+	}
+	// Cruise Control accepts requests to endpoints requiring review by adding them to its purgatory, so the only
+	// review related failures are submitting requests which are not in the purgatory or are not approved.
+	reviewRequiredPatterns = []string{
+		"exists in purgatory",
+		"awaiting review",
+		"not approved",
+		"not been approved",
+	}
+	goalOptimizationPatterns = []string{
+		"optimizationfailureexception",
+		"optimization failure",
+	}
+	badRequestPatterns = []string{
+		"userrequestexception",
+		"illegalargumentexception",
+		"unrecognized endpoint parameter",
+	}
+
+	badRequestParamListRegexp = regexp.MustCompile(`(?i)parameters?[^\[]*\[([a-z_, ]+)\]`)
+	badRequestParamRegexp     = regexp.MustCompile(`(?i)parameter\s*(?:[:=]\s*["']?|["'])([a-z][a-z_]*)`)
+	goalNameRegexp            = regexp.MustCompile(`\b([A-Z][A-Za-z]*Goal)\b`)
+	reviewIDRegexp            = regexp.MustCompile(`(?i)review[ _]?id\s*[:=]?\s*(\d+)`)
+)
+
+// CruiseControlError is the base of the errors returned for failed Cruise Control requests.
+// It keeps the raw error response returned by Cruise Control and can be used with errors.As to handle
+// every Cruise Control failure in the same way.
+type CruiseControlError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// RequestURL is the URL of the failed request.
+	RequestURL string
+	// TaskID is the ID of the user task the failed request belongs to, if any.
+	TaskID string
+	// Response is the raw error response returned by Cruise Control.
+	Response *APIError
+}
+
+func (e *CruiseControlError) Error() string {
+	if e.Response == nil {
+		return fmt.Sprintf("request failed with status %d", e.StatusCode)
+	}
+	return e.Response.Error()
+}
+
+func (e *CruiseControlError) Unwrap() error {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response
+}
+
+// message returns the error message and the stack trace of the response for classification.
+func (e *CruiseControlError) message() (string, string) {
+	if e.Response == nil {
+		return "", ""
+	}
+	msg := e.Response.ErrorMessage
+	if msg == "" {
+		msg = e.Response.Message
+	}
+	return msg, e.Response.StackTrace
+}
+
+// NotReadyError is returned if Cruise Control cannot serve the request yet as its load monitor is still
+// bootstrapping or has not collected enough valid metric windows.
+type NotReadyError struct {
+	*CruiseControlError
+}
+
+func (e *NotReadyError) Error() string {
+	return "Cruise Control is not ready: " + e.CruiseControlError.Error()
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// OngoingExecutionError is returned if the request conflicts with an ongoing proposal execution.
+type OngoingExecutionError struct {
+	*CruiseControlError
+}
+
+func (e *OngoingExecutionError) Error() string {
+	return "conflict with ongoing execution: " + e.CruiseControlError.Error()
+}
+
+func (e *OngoingExecutionError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// PermissionDeniedError is returned if the user is not authenticated or not allowed to perform the request.
+type PermissionDeniedError struct {
+	*CruiseControlError
+}
+
+func (e *PermissionDeniedError) Error() string {
+	return "permission denied: " + e.CruiseControlError.Error()
+}
+
+func (e *PermissionDeniedError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// ReviewRequiredError is returned if two-step verification is enabled and the request submitted with a review ID
+// is either not in the purgatory of Cruise Control or has not been approved on the review board yet.
+type ReviewRequiredError struct {
+	*CruiseControlError
+	// ReviewID is the ID of the request on the review board if Cruise Control reported it.
+	ReviewID int32
+}
+
+func (e *ReviewRequiredError) Error() string {
+	if e.ReviewID != 0 {
+		return fmt.Sprintf("review required (review id: %d): %s", e.ReviewID, e.CruiseControlError.Error())
+	}
+	return "review required: " + e.CruiseControlError.Error()
+}
+
+func (e *ReviewRequiredError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// BadRequestError is returned if Cruise Control rejected the request parameters.
+type BadRequestError struct {
+	*CruiseControlError
+	// Params holds the names of the offending request parameters if they could be determined.
+	Params []string
+}
+
+func (e *BadRequestError) Error() string {
+	if len(e.Params) > 0 {
+		return fmt.Sprintf("bad request (parameters: %s): %s", strings.Join(e.Params, ", "), e.CruiseControlError.Error())
+	}
+	return "bad request: " + e.CruiseControlError.Error()
+}
+
+func (e *BadRequestError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// Param returns the first offending request parameter or an empty string if it is unknown.
+func (e *BadRequestError) Param() string {
+	if len(e.Params) == 0 {
+		return ""
+	}
+	return e.Params[0]
+}
+
+// GoalOptimizationError is returned if Cruise Control failed to generate proposals satisfying a goal.
+type GoalOptimizationError struct {
+	*CruiseControlError
+	// GoalName is the name of the goal which failed as reported by Cruise Control.
+	GoalName string
+	// Goal is the failed goal or UndefinedGoal if it is not known by this client.
+	Goal Goal
+}
+
+func (e *GoalOptimizationError) Error() string {
+	if e.GoalName != "" {
+		return fmt.Sprintf("optimization of goal %s failed: %s", e.GoalName, e.CruiseControlError.Error())
+	}
+	return "goal optimization failed: " + e.CruiseControlError.Error()
+}
+
+func (e *GoalOptimizationError) Unwrap() error {
+	return e.CruiseControlError
+}
+
+// TransportError is returned if the request could not be sent to Cruise Control or its response could not
+// be received.
+type TransportError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("sending HTTP request %s %s failed: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the request failed due to a timeout.
+func (e *TransportError) Timeout() bool {
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// ClassifyError returns the typed error for the error response of a failed Cruise Control request
+// based on the status code of the response and the error message and stack trace returned by Cruise Control.
+// The returned error is a *CruiseControlError if the failure does not fall into any of the known categories.
+func ClassifyError(statusCode int, requestURL, taskID string, apiErr *APIError) error {
+	if statusCode == 0 && apiErr != nil {
+		statusCode, _ = strconv.Atoi(apiErr.Status)
+	}
+	base := &CruiseControlError{
+		StatusCode: statusCode,
+		RequestURL: requestURL,
+		TaskID:     taskID,
+		Response:   apiErr,
+	}
+
+	msg, stackTrace := base.message()
+	text := strings.ToLower(msg + "\n" + stackTrace)
+
+	switch {
+	// Stack traces of unrelated failures can mention the security handlers of the servlet, so permission
+	// failures are only detected from the error message.
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden ||
+		containsAny(strings.ToLower(msg), permissionDeniedPatterns):
+		return &PermissionDeniedError{CruiseControlError: base}
+	case containsAny(text, reviewRequiredPatterns):
+		err := &ReviewRequiredError{CruiseControlError: base}
+		if m := reviewIDRegexp.FindStringSubmatch(msg); m != nil {
+			if id, convErr := strconv.ParseInt(m[1], 10, 32); convErr == nil {
+				err.ReviewID = int32(id)
+			}
+		}
+		return err
+	case statusCode == http.StatusConflict || containsAny(text, ongoingExecutionPatterns):
+		return &OngoingExecutionError{CruiseControlError: base}
+	case statusCode == http.StatusServiceUnavailable || containsAny(text, notReadyPatterns):
+		return &NotReadyError{CruiseControlError: base}
+	case containsAny(text, goalOptimizationPatterns):
+		err := &GoalOptimizationError{CruiseControlError: base}
+		if m := goalNameRegexp.FindStringSubmatch(msg); m != nil {
+			err.GoalName = m[1]
+		} else if m = goalNameRegexp.FindStringSubmatch(stackTrace); m != nil {
+			err.GoalName = m[1]
+		}
+		_ = err.Goal.UnmarshalText([]byte(err.GoalName))
+		return err
+	case statusCode == http.StatusBadRequest || containsAny(text, badRequestPatterns):
+		return &BadRequestError{CruiseControlError: base, Params: offendingParams(msg)}
+	}
+	return base
+}
+
+func offendingParams(msg string) []string {
+	if m := badRequestParamListRegexp.FindStringSubmatch(msg); m != nil {
+		var params []string
+		for _, p := range strings.Split(m[1], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				params = append(params, p)
+			}
+		}
+		return params
+	}
+	if m := badRequestParamRegexp.FindStringSubmatch(msg); m != nil {
+		return []string{m[1]}
+	}
+	return nil
+}
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
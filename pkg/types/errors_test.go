@@ -0,0 +1,157 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Run("Not ready", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusInternalServerError, "http://cc/state", "", &APIError{
+			ErrorMessage: "There is no window available in range [-1, 1700000000000] (index [1, -1]).",
+			StackTrace:   "com.linkedin.cruisecontrol.exception.NotEnoughValidWindowsException: There is no window...",
+		})
+
+		var notReady *NotReadyError
+		g.Expect(errors.As(err, &notReady)).To(BeTrue())
+		var ccErr *CruiseControlError
+		g.Expect(errors.As(err, &ccErr)).To(BeTrue())
+		g.Expect(ccErr.StatusCode).To(Equal(http.StatusInternalServerError))
+		var apiErr *APIError
+		g.Expect(errors.As(err, &apiErr)).To(BeTrue())
+		g.Expect(apiErr.StackTrace).To(ContainSubstring("NotEnoughValidWindowsException"))
+	})
+
+	t.Run("Ongoing execution", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusInternalServerError, "", "", &APIError{
+			ErrorMessage: "Cannot start a new execution while there is an ongoing execution.",
+		})
+
+		var ongoing *OngoingExecutionError
+		g.Expect(errors.As(err, &ongoing)).To(BeTrue())
+	})
+
+	t.Run("Permission denied from servlet error", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(0, "", "", &APIError{Message: "Unauthorized", Status: "401"})
+
+		var denied *PermissionDeniedError
+		g.Expect(errors.As(err, &denied)).To(BeTrue())
+		g.Expect(denied.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	t.Run("Permission denied is not detected from stack trace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusInternalServerError, "", "", &APIError{
+			ErrorMessage: "Error processing POST request '/rebalance' due to: 'java.lang.NullPointerException'.",
+			StackTrace: "java.lang.NullPointerException\n\tat org.eclipse.jetty.security.SecurityHandler.handle(" +
+				"SecurityHandler.java:552)\n\tat com.linkedin.kafka.cruisecontrol.servlet.security.Unauthorized",
+		})
+
+		var denied *PermissionDeniedError
+		g.Expect(errors.As(err, &denied)).To(BeFalse())
+	})
+
+	t.Run("Review required", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusBadRequest, "", "", &APIError{
+			ErrorMessage: "No request with review id 42 exists in purgatory. Please use REVIEW_BOARD endpoint to check " +
+				"for the current requests awaiting review in purgatory.",
+			StackTrace: "com.linkedin.kafka.cruisecontrol.servlet.UserRequestException: ...",
+		})
+
+		var review *ReviewRequiredError
+		g.Expect(errors.As(err, &review)).To(BeTrue())
+		g.Expect(review.ReviewID).To(Equal(int32(42)))
+
+		err = ClassifyError(http.StatusBadRequest, "", "", &APIError{
+			ErrorMessage: "Request with review id 7 has not been approved (status: PENDING_REVIEW).",
+		})
+		g.Expect(errors.As(err, &review)).To(BeTrue())
+		g.Expect(review.ReviewID).To(Equal(int32(7)))
+	})
+
+	t.Run("Review ID without two-step verification is a bad request", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusBadRequest, "", "", &APIError{
+			ErrorMessage: "review_id parameter is not relevant when two-step verification is disabled.",
+			StackTrace:   "com.linkedin.kafka.cruisecontrol.servlet.UserRequestException: ...",
+		})
+
+		var review *ReviewRequiredError
+		g.Expect(errors.As(err, &review)).To(BeFalse())
+		var badRequest *BadRequestError
+		g.Expect(errors.As(err, &badRequest)).To(BeTrue())
+	})
+
+	t.Run("Bad request with offending parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusBadRequest, "", "", &APIError{
+			ErrorMessage: "Unrecognized endpoint parameters in REBALANCE post request: [foo_bar, baz].",
+			StackTrace:   "com.linkedin.kafka.cruisecontrol.servlet.UserRequestException: ...",
+		})
+
+		var badRequest *BadRequestError
+		g.Expect(errors.As(err, &badRequest)).To(BeTrue())
+		g.Expect(badRequest.Params).To(Equal([]string{"foo_bar", "baz"}))
+		g.Expect(badRequest.Param()).To(Equal("foo_bar"))
+	})
+
+	t.Run("Goal optimization failure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusInternalServerError, "", "", &APIError{
+			ErrorMessage: "[RackAwareGoal] Insufficient number of racks to distribute each replica (Current: 2, Needed: 3).",
+			StackTrace:   "com.linkedin.kafka.cruisecontrol.exception.OptimizationFailureException: ...",
+		})
+
+		var goalErr *GoalOptimizationError
+		g.Expect(errors.As(err, &goalErr)).To(BeTrue())
+		g.Expect(goalErr.GoalName).To(Equal("RackAwareGoal"))
+		g.Expect(goalErr.Goal).To(Equal(RackAwareGoal))
+	})
+
+	t.Run("Unknown failure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		err := ClassifyError(http.StatusInternalServerError, "", "", &APIError{ErrorMessage: "something went wrong"})
+
+		g.Expect(err).To(BeAssignableToTypeOf(&CruiseControlError{}))
+		g.Expect(err.Error()).To(Equal("something went wrong"))
+	})
+
+	t.Run("No error for successful response", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		r := &GenericResponse{StatusCode: http.StatusOK}
+		g.Expect(r.Err()).To(BeNil())
+	})
+}
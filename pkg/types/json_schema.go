@@ -0,0 +1,236 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	JSONSchemaTypeObject = "object"
+	JSONSchemaTypeArray  = "array"
+)
+
+// JSONSchema is the simplified JSON schema Cruise Control returns in the x-json-schema HTTP header if
+// the response schema is requested using the get_response_schema parameter.
+type JSONSchema struct {
+	Type       string                 `json:"type"`
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	Items      []*JSONSchema          `json:"items,omitempty"`
+}
+
+// ParseJSONSchema parses the JSON schema returned by Cruise Control.
+func ParseJSONSchema(s string) (*JSONSchema, error) {
+	schema := &JSONSchema{}
+	if err := json.Unmarshal([]byte(s), schema); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	return schema, nil
+}
+
+// ResponseDiagnostics holds the differences found between a Cruise Control response and the Go type
+// it was decoded into. Fields are referred to using JSON paths, e.g. $.ExecutorState.state
+type ResponseDiagnostics struct {
+	// Fields sent by the server which are missing from the Go type.
+	UnknownFields []string
+	// Fields of the Go type which are not sent by the server.
+	MissingFields []string
+}
+
+// Empty returns true if no difference was found.
+func (d *ResponseDiagnostics) Empty() bool {
+	return d == nil || len(d.UnknownFields) == 0 && len(d.MissingFields) == 0
+}
+
+// CompareJSONSchema compares the schema of a response with the Go type it is decoded into.
+// Fields tagged with omitempty are not reported as missing since the server omits them if they are empty.
+func CompareJSONSchema(schema *JSONSchema, t reflect.Type) *ResponseDiagnostics {
+	unknown := make(map[string]bool)
+	missing := make(map[string]bool)
+	compareJSONSchema(schema, t, "$", unknown, missing)
+
+	return &ResponseDiagnostics{
+		UnknownFields: sortedKeys(unknown),
+		MissingFields: sortedKeys(missing),
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type jsonField struct {
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+// isOpaqueType returns true for types which implement custom decoding, therefore their content is not compared.
+func isOpaqueType(t reflect.Type) bool {
+	unmarshalerType := reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	p := reflect.PointerTo(t)
+	return p.Implements(unmarshalerType) || p.Implements(textUnmarshalerType)
+}
+
+// jsonFields returns the fields of the struct type by their JSON names including the fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = jsonField{typ: f.Type, omitEmpty: strings.Contains(opts, "omitempty")}
+	}
+	return fields
+}
+
+func compareJSONSchema(schema *JSONSchema, t reflect.Type, path string, unknown, missing map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if schema == nil || isOpaqueType(t) {
+		return
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		if schema.Type != JSONSchemaTypeObject {
+			return
+		}
+		fields := jsonFields(t)
+		for name, prop := range schema.Properties {
+			f, ok := fields[name]
+			if !ok {
+				unknown[path+"."+name] = true
+				continue
+			}
+			compareJSONSchema(prop, f.typ, path+"."+name, unknown, missing)
+		}
+		for name, f := range fields {
+			if _, ok := schema.Properties[name]; !ok && !f.omitEmpty {
+				missing[path+"."+name] = true
+			}
+		}
+	case reflect.Map:
+		if schema.Type != JSONSchemaTypeObject {
+			return
+		}
+		for _, prop := range schema.Properties {
+			compareJSONSchema(prop, t.Elem(), path+".*", unknown, missing)
+		}
+	case reflect.Slice, reflect.Array:
+		if schema.Type != JSONSchemaTypeArray {
+			return
+		}
+		for _, item := range schema.Items {
+			compareJSONSchema(item, t.Elem(), path+"[*]", unknown, missing)
+		}
+	}
+}
+
+// UnknownJSONFields returns the JSON paths of the fields in the JSON document which have no corresponding field in
+// the Go type. Field names are matched the same way as encoding/json does, preferring exact matches but also
+// accepting case-insensitive ones.
+func UnknownJSONFields(data []byte, t reflect.Type) ([]string, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON document: %w", err)
+	}
+
+	unknown := make(map[string]bool)
+	unknownJSONFields(v, t, "$", unknown)
+	return sortedKeys(unknown), nil
+}
+
+func lookupJSONField(fields map[string]jsonField, name string) (jsonField, bool) {
+	if f, ok := fields[name]; ok {
+		return f, true
+	}
+	for k, f := range fields {
+		if strings.EqualFold(k, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func unknownJSONFields(v interface{}, t reflect.Type, path string, unknown map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if v == nil || isOpaqueType(t) {
+		return
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for name, value := range obj {
+			f, ok := lookupJSONField(fields, name)
+			if !ok {
+				unknown[path+"."+name] = true
+				continue
+			}
+			unknownJSONFields(value, f.typ, path+"."+name, unknown)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, value := range obj {
+			unknownJSONFields(value, t.Elem(), path+".*", unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range items {
+			unknownJSONFields(item, t.Elem(), path+"[*]", unknown)
+		}
+	}
+}
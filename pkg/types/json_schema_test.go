@@ -0,0 +1,69 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Run("Compare schema with type", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		schema, err := ParseJSONSchema(`{"type": "object", "properties": {
+			"version": {"type": "number"},
+			"userTasks": {"type": "array", "items": [{"type": "object", "properties": {
+				"UserTaskId": {"type": "string"},
+				"RequestURL": {"type": "string"},
+				"ClientIdentity": {"type": "string"},
+				"StartMs": {"type": "number"},
+				"Status": {"type": "string"},
+				"Retries": {"type": "number"}
+			}}]}
+		}}`)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		diagnostics := CompareJSONSchema(schema, reflect.TypeOf(UserTaskState{}))
+		g.Expect(diagnostics.UnknownFields).To(Equal([]string{"$.userTasks[*].Retries"}))
+		g.Expect(diagnostics.MissingFields).To(Equal([]string{"$.userTasks[*].originalResponse"}))
+	})
+
+	t.Run("Find unknown fields", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		body := []byte(`{
+			"version": 1,
+			"KafkaBrokerState": {
+				"leadercountbybrokerid": {"0": 10},
+				"Summary": {"Brokers": 3, "Racks": 3}
+			},
+			"KafkaPartitionState": {
+				"urp": [{"topic": "test", "partition": 0, "replicas": [0, 1], "epoch": 5}]
+			}
+		}`)
+
+		unknown, err := UnknownJSONFields(body, reflect.TypeOf(&KafkaClusterState{}))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(unknown).To(Equal([]string{
+			"$.KafkaBrokerState.Summary.Racks",
+			"$.KafkaPartitionState.urp[*].epoch",
+		}))
+	})
+}
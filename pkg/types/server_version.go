@@ -0,0 +1,101 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ServerVersion is the version of Cruise Control reported in the Cruise-Control-Version HTTP header.
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+	// Suffix holds the pre-release or build information, e.g. SNAPSHOT.
+	Suffix string
+}
+
+// ParseServerVersion parses versions in the <major>.<minor>.<patch>[-suffix] format.
+func ParseServerVersion(s string) (ServerVersion, error) {
+	v := ServerVersion{}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if idx := strings.IndexAny(s, "-+"); idx >= 0 {
+		v.Suffix = s[idx+1:]
+		s = s[:idx]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 { //nolint:gomnd
+		return ServerVersion{}, errors.Errorf("invalid Cruise Control version: %q", s)
+	}
+
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return ServerVersion{}, errors.Errorf("invalid Cruise Control version: %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+
+	return v, nil
+}
+
+// MustParseServerVersion is like ParseServerVersion but panics if the version cannot be parsed.
+func MustParseServerVersion(s string) ServerVersion {
+	v, err := ParseServerVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v ServerVersion) String() string {
+	if v.Suffix != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, v.Suffix)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero returns true if the version is not set.
+func (v ServerVersion) IsZero() bool {
+	return v == ServerVersion{}
+}
+
+// Compare returns -1, 0 or 1 if v is lower, equal or higher than o. The suffix is not taken into account.
+func (v ServerVersion) Compare(o ServerVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast returns true if v is equal or higher than o.
+func (v ServerVersion) AtLeast(o ServerVersion) bool {
+	return v.Compare(o) >= 0
+}
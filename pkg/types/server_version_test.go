@@ -0,0 +1,70 @@
+/*
+Copyright © 2021 Cisco and/or its affiliates. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ServerVersion
+		invalid  bool
+	}{
+		{name: "Release", input: "2.5.101", expected: ServerVersion{Major: 2, Minor: 5, Patch: 101}},
+		{name: "Prefixed", input: " v2.5.94 ", expected: ServerVersion{Major: 2, Minor: 5, Patch: 94}},
+		{name: "Pre-release", input: "2.5.113-SNAPSHOT", expected: ServerVersion{Major: 2, Minor: 5, Patch: 113, Suffix: "SNAPSHOT"}},
+		{name: "Build metadata", input: "2.5.97+build.7", expected: ServerVersion{Major: 2, Minor: 5, Patch: 97, Suffix: "build.7"}},
+		{name: "Empty", input: "", invalid: true},
+		{name: "Missing patch", input: "2.5", invalid: true},
+		{name: "Too many parts", input: "2.5.1.1", invalid: true},
+		{name: "Not a number", input: "2.x.1", invalid: true},
+		{name: "Negative", input: "2.-5.1", invalid: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			v, err := ParseServerVersion(test.input)
+			if test.invalid {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(v.IsZero()).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(v).To(Equal(test.expected))
+		})
+	}
+}
+
+func TestServerVersionCompare(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	v := MustParseServerVersion("2.5.97")
+	g.Expect(v.AtLeast(MustParseServerVersion("2.5.97-SNAPSHOT"))).To(BeTrue())
+	g.Expect(v.AtLeast(MustParseServerVersion("2.5.94"))).To(BeTrue())
+	g.Expect(v.AtLeast(MustParseServerVersion("2.5.101"))).To(BeFalse())
+	g.Expect(v.Compare(MustParseServerVersion("3.0.0"))).To(Equal(-1))
+	g.Expect(v.Compare(MustParseServerVersion("2.4.200"))).To(Equal(1))
+	g.Expect(func() { MustParseServerVersion("latest") }).To(Panic())
+}